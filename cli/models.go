@@ -75,8 +75,8 @@ func (dsObj *Entity) ExtractCSV() *[][]string {
 
 	if dsObj.ParentKey != nil {
 
-		srcId := strconv.FormatInt(dsObj.Key.IntID(), 10)
-		destId := strconv.FormatInt(dsObj.ParentKey.IntID(), 10)
+		srcId := KeyId(dsObj.Key)
+		destId := KeyId(dsObj.ParentKey)
 		id := srcId + ":" + destId
 
 		parentCSV := []string{
@@ -99,8 +99,8 @@ func (dsObj *Entity) ExtractCSV() *[][]string {
 	if dsObj.EntityKeys != nil {
 		for _, entityKey := range dsObj.EntityKeys {
 
-			srcId := strconv.FormatInt(dsObj.Key.IntID(), 10)
-			destId := strconv.FormatInt(entityKey.IntID(), 10)
+			srcId := KeyId(dsObj.Key)
+			destId := KeyId(entityKey)
 			id := srcId + ":" + destId
 
 			tagCSV := []string{
@@ -153,8 +153,8 @@ func (dsObj *Follow) ExtractCSV() *[][]string {
 		score = strconv.FormatInt(UnixMilli(&dsObj.Created), 10)
 	}
 
-	srcId := strconv.FormatInt(dsObj.SrcKey.IntID(), 10)
-	destId := strconv.FormatInt(dsObj.DestKey.IntID(), 10)
+	srcId := KeyId(dsObj.SrcKey)
+	destId := KeyId(dsObj.DestKey)
 	id := srcId + ":" + destId
 
 	data := "null"
@@ -182,6 +182,17 @@ func (dsObj *Follow) ExtractCSV() *[][]string {
 	return &[][]string{edgeCSV}
 }
 
+// KeyId is the node id of an entity, its key name when it has one,
+// so entities keyed by names or UUIDs go to edges with string ids.
+func KeyId(key *ds_to_sql.Key) string {
+
+	if key.StringID() != "" {
+		return key.StringID()
+	}
+
+	return strconv.FormatInt(key.IntID(), 10)
+}
+
 func UnixMilli(ts *time.Time) int64 {
 	return ts.UnixNano() / int64(time.Millisecond)
 }
@@ -5,6 +5,7 @@ import (
 	"database/sql"
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 
 	"github.com/ExpansiveWorlds/instrumentedsql"
@@ -13,31 +14,150 @@ import (
 )
 
 const (
+	// Counts tables are named after their edge tables, so the names of
+	// edge tables cannot end with it.
+	COUNTS_SUFFIX string = "_counts"
+
+	// Tables created before edges had a `qualifier` get the column
+	// when they are created again. Node ids are of the column type
+	// of the edge type's `id_type`.
 	DML_CREATE_EDGE_TABLE string = `
-	  CREATE TABLE IF NOT EXISTS %s (
+	  CREATE TABLE IF NOT EXISTS %[1]s (
 	    id varchar PRIMARY KEY,
-	    src_id bigint,
+	    src_id %[2]s,
 	    src_type varchar,
-	    dest_id bigint,
+	    dest_id %[2]s,
 	    dest_type varchar,
+	    qualifier varchar,
 	    score decimal,
 	    data jsonb,
 	    status varchar,
 	    updated timestamp
 	  );
+	  ALTER TABLE %[1]s ADD COLUMN IF NOT EXISTS qualifier varchar;
+	`
+
+	// Tables of registered edge types that were created before edges
+	// had a `qualifier`. Tables of namespaces are named
+	// `namespace.name`.
+	DML_SELECT_UNQUALIFIED_TABLES string = `
+	  SELECT CASE WHEN t.namespace = '' THEN t.name
+	              ELSE t.namespace || '.' || t.name END AS name
+	  FROM loki_edge_types t
+	  JOIN information_schema.tables c
+	    ON c.table_schema = CASE WHEN t.namespace = '' THEN current_schema() ELSE t.namespace END
+	   AND c.table_name = t.name
+	  WHERE NOT EXISTS (
+	    SELECT 1 FROM information_schema.columns q
+	    WHERE q.table_schema = c.table_schema AND q.table_name = c.table_name
+	      AND q.column_name = 'qualifier'
+	  )
+	  ORDER BY t.namespace, t.name
+	`
+
+	DML_ADD_QUALIFIER string = "ALTER TABLE %s ADD COLUMN IF NOT EXISTS qualifier varchar"
+
+	// Indexes are named after the table without its namespace, they
+	// are always created in the schema of the table.
+	DML_CREATE_INDEX string = "CREATE INDEX IF NOT EXISTS %s ON %s (%s)"
+
+	DML_CREATE_SCHEMA string = "CREATE SCHEMA IF NOT EXISTS %s"
+
+	// Optional per edge type counters of active edges, by direction.
+	DML_CREATE_COUNTS_TABLE string = `
+	  CREATE TABLE IF NOT EXISTS %s (
+	    node_id %s,
+	    direction varchar,
+	    count bigint NOT NULL DEFAULT 0,
+	    PRIMARY KEY (node_id, direction)
+	  );
+	`
+
+	DML_COUNTS_ID_TYPE string = `
+	  SELECT format_type(atttypid, atttypmod)
+	  FROM pg_attribute
+	  WHERE attrelid = to_regclass($1) AND attname = 'node_id'
+	`
+
+	// Tables loki keeps its own state in, created at startup.
+	DML_CREATE_SYSTEM_TABLES string = `
+	  CREATE TABLE IF NOT EXISTS loki_feeds (
+	    name varchar PRIMARY KEY,
+	    content_edge varchar NOT NULL,
+	    follow_edge varchar NOT NULL,
+	    fanout_limit integer NOT NULL,
+	    created timestamp NOT NULL DEFAULT now()
+	  );
+	  CREATE TABLE IF NOT EXISTS loki_edge_types (
+	    namespace varchar NOT NULL DEFAULT '',
+	    name varchar NOT NULL,
+	    src_types varchar[],
+	    dest_types varchar[],
+	    schema jsonb,
+	    options jsonb,
+	    created timestamp NOT NULL DEFAULT now(),
+	    PRIMARY KEY (namespace, name)
+	  );
+	  CREATE TABLE IF NOT EXISTS loki_api_keys (
+	    id varchar PRIMARY KEY,
+	    name varchar NOT NULL,
+	    secret_hash varchar NOT NULL,
+	    scopes jsonb NOT NULL,
+	    created timestamp NOT NULL DEFAULT now(),
+	    revoked timestamp
+	  );
 	`
 
-	DML_DEFAULT_INDEXES string = `
-	  CREATE INDEX IF NOT EXISTS %[1]s_src_id ON %[1]s (src_id);
-	  CREATE INDEX IF NOT EXISTS %[1]s_dest_id ON %[1]s (dest_id);
-	  CREATE INDEX IF NOT EXISTS %[1]s_score ON %[1]s (score);
-	  CREATE INDEX IF NOT EXISTS %[1]s_status ON %[1]s (status);
-	  CREATE INDEX IF NOT EXISTS %[1]s_combi ON %[1]s (src_id, dest_id, score, status);
+	// Inbox of items fanned out to every follower & the authors that
+	// have too many followers, whose items are merged on read.
+	DML_CREATE_FEED_TABLES string = `
+	  CREATE TABLE IF NOT EXISTS %[1]s (
+	    user_id bigint,
+	    edge_id varchar,
+	    score decimal,
+	    PRIMARY KEY (user_id, edge_id)
+	  );
+	  CREATE INDEX IF NOT EXISTS %[3]s ON %[1]s (user_id, score, edge_id);
+	  CREATE INDEX IF NOT EXISTS %[4]s ON %[1]s (edge_id);
+	  CREATE TABLE IF NOT EXISTS %[2]s (
+	    author_id bigint PRIMARY KEY
+	  );
 	`
 
 	DML_DROP_TABLE string = "DROP TABLE %s"
+
+	DML_DROP_TABLE_IF_EXISTS string = "DROP TABLE IF EXISTS %s"
 )
 
+// Index is an index of edge tables, named after the table with the
+// suffix.
+type Index struct {
+	Suffix  string
+	Columns string
+}
+
+var DEFAULT_INDEXES = []Index{
+	{"_src_id", "src_id"},
+	{"_dest_id", "dest_id"},
+	{"_score", "score"},
+	{"_status", "status"},
+	{"_combi", "src_id, dest_id, score, status"},
+	{"_src_score", "src_id, score, id"},
+	{"_dest_score", "dest_id, score, id"},
+	{"_src_updated", "src_id, updated, id"},
+	{"_dest_updated", "dest_id, updated, id"},
+}
+
+// Names of namespaces, edge types & feeds are lowercase identifiers,
+// short enough for the names of the tables & indexes made from them
+// to fit in the 63 bytes of a Postgres identifier.
+var namePattern = regexp.MustCompile(`^[a-z_][a-z0-9_]{0,39}$`)
+
+// Edge tables share their schema with the tables of loki, `loki_..`,
+// and of feeds, `feed_..`, & SQLite keeps its own tables as
+// `sqlite_..`, so the names of edge tables cannot start with them.
+var RESERVED_PREFIXES = []string{"loki_", "feed_", "sqlite_"}
+
 func InitDB(databaseURL string) *sqlx.DB {
 
 	dbDriver, err := sql.Open("postgres", databaseURL)
@@ -66,9 +186,88 @@ func InitDebugDB(databaseURL string) *sqlx.DB {
 	return Db
 }
 
-func CreateTable(Db *sqlx.DB, tableName string) error {
+// SplitName splits a `namespace.name` table name. Tables without a
+// namespace are in the default schema.
+func SplitName(tableName string) (string, string) {
+
+	if idx := strings.Index(tableName, "."); idx >= 0 {
+		return tableName[:idx], tableName[idx+1:]
+	}
+
+	return "", tableName
+}
+
+func QualifyName(namespace string, name string) string {
+
+	if namespace == "" {
+		return name
+	}
+
+	return namespace + "." + name
+}
+
+// ValidName tells if a name of a namespace, an edge type or a feed
+// follows the grammar of names, see namePattern.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// ValidTableName tells if both parts of a `namespace.name` table
+// name are valid names, and the name is not that of a counts table
+// or of a table with one of the RESERVED_PREFIXES.
+func ValidTableName(tableName string) bool {
+
+	namespace, name := SplitName(tableName)
+
+	if namespace != "" && !ValidName(namespace) {
+		return false
+	}
+
+	if !ValidName(name) || strings.HasSuffix(name, COUNTS_SUFFIX) {
+		return false
+	}
+
+	for _, prefix := range RESERVED_PREFIXES {
+		if strings.HasPrefix(name, prefix) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// QuoteName quotes the namespace & name of a table name, to use it
+// as an identifier in SQL.
+func QuoteName(tableName string) string {
+
+	namespace, name := SplitName(tableName)
+
+	if namespace == "" {
+		return pq.QuoteIdentifier(name)
+	}
+
+	return pq.QuoteIdentifier(namespace) + "." + pq.QuoteIdentifier(name)
+}
+
+// CreateSchema creates the schema of a namespace, on demand.
+func CreateSchema(Db *sqlx.DB, namespace string) error {
+
+	query := fmt.Sprintf(DML_CREATE_SCHEMA, pq.QuoteIdentifier(namespace))
+
+	_, err := Db.Exec(query)
+
+	return err
+}
+
+func CreateTable(Db *sqlx.DB, tableName string, idColumnType string) error {
 
-	query := fmt.Sprintf(DML_CREATE_EDGE_TABLE, tableName)
+	if namespace, _ := SplitName(tableName); namespace != "" {
+		if err := CreateSchema(Db, namespace); err != nil {
+			return err
+		}
+	}
+
+	query := fmt.Sprintf(DML_CREATE_EDGE_TABLE, QuoteName(tableName), idColumnType)
 
 	_, err := Db.Exec(query)
 
@@ -76,19 +275,140 @@ func CreateTable(Db *sqlx.DB, tableName string) error {
 }
 
 func CreateDefaultIndexes(Db *sqlx.DB, tableName string) error {
-	query := fmt.Sprintf(DML_DEFAULT_INDEXES, tableName)
+
+	_, name := SplitName(tableName)
+
+	for _, index := range DEFAULT_INDEXES {
+
+		query := fmt.Sprintf(DML_CREATE_INDEX, pq.QuoteIdentifier(name+index.Suffix), QuoteName(tableName), index.Columns)
+
+		if _, err := Db.Exec(query); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func CreateCountsTable(Db *sqlx.DB, tableName string, idColumnType string) error {
+
+	query := fmt.Sprintf(DML_CREATE_COUNTS_TABLE, QuoteName(CountsTableName(tableName)), idColumnType)
+
+	_, err := Db.Exec(query)
+
+	return err
+}
+
+// HasTable tells if the table exists, in the schema of its namespace.
+func HasTable(Db sqlx.Queryer, tableName string) (bool, error) {
+
+	var exists bool
+
+	err := sqlx.Get(Db, &exists, "SELECT to_regclass($1) IS NOT NULL", QuoteName(tableName))
+
+	return exists, err
+}
+
+func HasCountsTable(Db sqlx.Queryer, tableName string) (bool, error) {
+	return HasTable(Db, CountsTableName(tableName))
+}
+
+// CountsIdType is the type of the node ids in the counts table of
+// an edge table, or empty when the edge table has no counters.
+func CountsIdType(Db sqlx.Queryer, tableName string) (string, error) {
+
+	var idType string
+
+	err := sqlx.Get(Db, &idType, DML_COUNTS_ID_TYPE, QuoteName(CountsTableName(tableName)))
+
+	if err == sql.ErrNoRows {
+		return "", nil
+	}
+
+	return idType, err
+}
+
+func CountsTableName(tableName string) string {
+	return tableName + COUNTS_SUFFIX
+}
+
+func CreateSystemTables(Db *sqlx.DB) error {
+
+	_, err := Db.Exec(DML_CREATE_SYSTEM_TABLES)
+
+	return err
+}
+
+// MigrateEdgeTables adds the `qualifier` column to the tables of
+// registered edge types created before edges had one, and returns the
+// names of the tables it migrated. Other tables are never altered.
+func MigrateEdgeTables(Db *sqlx.DB) ([]string, error) {
+
+	tableNames := make([]string, 0)
+
+	if err := Db.Select(&tableNames, DML_SELECT_UNQUALIFIED_TABLES); err != nil {
+		return nil, err
+	}
+
+	for _, tableName := range tableNames {
+
+		if _, err := Db.Exec(fmt.Sprintf(DML_ADD_QUALIFIER, QuoteName(tableName))); err != nil {
+			return nil, err
+		}
+	}
+
+	return tableNames, nil
+}
+
+func CreateFeedTables(Db *sqlx.DB, feedName string) error {
+
+	namespace, name := SplitName(feedName)
+
+	if namespace != "" {
+		if err := CreateSchema(Db, namespace); err != nil {
+			return err
+		}
+	}
+
+	query := fmt.Sprintf(
+		DML_CREATE_FEED_TABLES,
+		QuoteName(FeedInboxTableName(feedName)), QuoteName(FeedPullTableName(feedName)),
+		pq.QuoteIdentifier("feed_"+name+"_inbox_user_score"), pq.QuoteIdentifier("feed_"+name+"_inbox_edge_id"),
+	)
 
 	_, err := Db.Exec(query)
 
 	return err
 }
 
+func FeedInboxTableName(feedName string) string {
+
+	namespace, name := SplitName(feedName)
+
+	return QualifyName(namespace, "feed_"+name+"_inbox")
+}
+
+func FeedPullTableName(feedName string) string {
+
+	namespace, name := SplitName(feedName)
+
+	return QualifyName(namespace, "feed_"+name+"_pull")
+}
+
 func DropTable(Db *sqlx.DB, tableName string) error {
 
-	query := fmt.Sprintf(DML_DROP_TABLE, tableName)
+	query := fmt.Sprintf(DML_DROP_TABLE, QuoteName(tableName))
 
 	_, err := Db.Exec(query)
 
+	if err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(DML_DROP_TABLE_IF_EXISTS, QuoteName(CountsTableName(tableName)))
+
+	_, err = Db.Exec(query)
+
 	return err
 }
 
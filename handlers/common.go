@@ -2,24 +2,32 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
 	"log"
 	"net/http"
+	"os"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
-	"github.com/jmoiron/sqlx"
+	"github.com/sonnes/loki/models"
 )
 
-func AttachDB(db *sqlx.DB, fn func(*sqlx.DB, http.ResponseWriter, *http.Request)) http.HandlerFunc {
+func AttachStore(store models.EdgeStore, fn func(models.EdgeStore, http.ResponseWriter, *http.Request)) http.HandlerFunc {
 
 	return func(w http.ResponseWriter, r *http.Request) {
-		fn(db, w, r)
+		fn(store, w, r)
 	}
 }
 
-func CreateRouter(Db *sqlx.DB) *chi.Mux {
+func CreateRouter(store models.EdgeStore, limits *RateLimits) *chi.Mux {
 	mux := chi.NewMux()
 
+	requestLimits, err := RequestLimitsFromEnv()
+
+	if err != nil {
+		log.Fatalf("could not read the request limits: %v\n", err)
+	}
+
 	mux.Use(middleware.Recoverer)
 	mux.Use(middleware.StripSlashes)
 	mux.Use(middleware.NoCache)
@@ -30,12 +38,44 @@ func CreateRouter(Db *sqlx.DB) *chi.Mux {
 
 	mux.Route("/v1", func(api chi.Router) {
 
+		api.Use(requestLimits.Limit)
+
+		if os.Getenv("API_KEYS_ENABLED") == "true" {
+			api.Use(Authenticate(store, limits))
+		}
+
+		// after authentication, so clients with keys are limited by key,
+		// requests that fail it are limited by IP in Authenticate
+		api.Use(limits.Limit)
+
 		jsonRequired := middleware.AllowContentType("application/json")
 
-		api.With(jsonRequired).Post("/edges/init", AttachDB(Db, InitEdgeEndpoint))
-		api.With(jsonRequired).Post("/edges/save", AttachDB(Db, SaveEdgesEndpoint))
-		api.With(jsonRequired).Post("/edges/delete", AttachDB(Db, DeleteEdgesEndpoint))
-		api.With(jsonRequired).Post("/edges/query", AttachDB(Db, RunQueryEndpoint))
+		api.With(jsonRequired).Post("/edges/init", AttachStore(store, InitEdgeEndpoint))
+		api.With(jsonRequired).Post("/edges/save", AttachStore(store, SaveEdgesEndpoint))
+		api.With(jsonRequired).Post("/edges/delete", AttachStore(store, DeleteEdgesEndpoint))
+		api.With(jsonRequired).Post("/edges/batch", AttachStore(store, BatchEdgesEndpoint))
+		api.With(jsonRequired).Post("/edges/query", AttachStore(store, RunQueryEndpoint))
+		api.With(jsonRequired).Post("/edges/count", AttachStore(store, CountEdgesEndpoint))
+		api.With(jsonRequired).Post("/edges/sets", AttachStore(store, CombineSetsEndpoint))
+		api.With(jsonRequired).Post("/edges/traverse", AttachStore(store, TraverseEndpoint))
+
+		api.Get("/edges/types", AttachStore(store, ListEdgeTypesEndpoint))
+		api.Get("/edges/types/{name}", AttachStore(store, GetEdgeTypeEndpoint))
+
+		api.With(jsonRequired).Post("/feeds/init", AttachStore(store, InitFeedEndpoint))
+		api.Get("/feeds/{name}", AttachStore(store, ReadFeedEndpoint))
+
+		// without authentication every client would administer keys,
+		// & keys minted then would stay valid once it is enabled
+		if os.Getenv("API_KEYS_ENABLED") == "true" {
+			api.With(jsonRequired).Post("/keys", AttachStore(store, CreateKeyEndpoint))
+			api.Get("/keys", AttachStore(store, ListKeysEndpoint))
+			api.Delete("/keys/{id}", AttachStore(store, RevokeKeyEndpoint))
+		}
+
+		if os.Getenv("RAW_SQL_ENABLED") == "true" {
+			api.With(jsonRequired).Post("/edges/sql", AttachStore(store, RunSQLEndpoint))
+		}
 
 	})
 
@@ -56,9 +96,14 @@ func WriteJson(w http.ResponseWriter, v interface{}, code int) {
 }
 
 type AppError struct {
-	Code    int       `json:"code"`
-	Message string    `json:"message"`
-	Fields  *[]string `json:"fields"`
+	Code    int                          `json:"code"`
+	Message string                       `json:"message"`
+	Fields  *[]string                    `json:"fields"`
+	Failed  *[]models.FailedPrecondition `json:"failed,omitempty"`
+}
+
+func (appErr *AppError) Error() string {
+	return appErr.Message
 }
 
 func WriteError(w http.ResponseWriter, appErr *AppError) {
@@ -76,3 +121,103 @@ func WriteError(w http.ResponseWriter, appErr *AppError) {
 	w.Write(errorJson)
 
 }
+
+// ValidationAppError is a 400 that points at the offending fields
+// when err is a models validation error.
+func ValidationAppError(err error) *AppError {
+
+	appErr := &AppError{
+		Code:    http.StatusBadRequest,
+		Message: err.Error(),
+	}
+
+	if validationErr, ok := err.(*models.ValidationError); ok {
+		appErr.Fields = &[]string{validationErr.Field}
+	}
+
+	if schemaErr, ok := err.(*models.SchemaError); ok {
+		appErr.Fields = &schemaErr.Fields
+	}
+
+	return appErr
+}
+
+func WriteValidationError(w http.ResponseWriter, err error) {
+	WriteError(w, ValidationAppError(err))
+}
+
+// ModelAppError is a 400 for validation errors, a 404 for writes of
+// unknown edges, a 409 for failed preconditions, a 429 for clients
+// out of their budget and a 500 for every other error from the
+// models. Application errors are passed through.
+func ModelAppError(err error) *AppError {
+
+	if appErr, ok := err.(*AppError); ok {
+		return appErr
+	}
+
+	if models.IsValidationError(err) {
+		return ValidationAppError(err)
+	}
+
+	if preconditionErr, ok := err.(*models.PreconditionError); ok {
+		return preconditionAppError(preconditionErr)
+	}
+
+	if _, ok := err.(*models.UnknownEdgeError); ok {
+		return &AppError{
+			Code:    http.StatusNotFound,
+			Message: err.Error(),
+		}
+	}
+
+	if _, ok := err.(*RateLimitedError); ok {
+		return &AppError{
+			Code:    http.StatusTooManyRequests,
+			Message: err.Error(),
+		}
+	}
+
+	return &AppError{
+		Code:    http.StatusInternalServerError,
+		Message: err.Error(),
+	}
+}
+
+func WriteModelError(w http.ResponseWriter, err error) {
+
+	if limitedErr, ok := err.(*RateLimitedError); ok {
+		WriteRateLimited(w, limitedErr.Wait, limitedErr.Message)
+		return
+	}
+
+	WriteError(w, ModelAppError(err))
+}
+
+// preconditionAppError is a 409 that lists the edges whose
+// preconditions failed.
+func preconditionAppError(err *models.PreconditionError) *AppError {
+
+	fields := make([]string, len(err.Edges))
+
+	for idx, failed := range err.Edges {
+		fields[idx] = failed.Field
+	}
+
+	return &AppError{
+		Code:    http.StatusConflict,
+		Message: err.Error(),
+		Fields:  &fields,
+		Failed:  &err.Edges,
+	}
+}
+
+// WriteUnsupported answers with a 501 for the queries the configured
+// store cannot run.
+func WriteUnsupported(w http.ResponseWriter, feature string) {
+
+	WriteError(w, &AppError{
+		Code:    http.StatusNotImplemented,
+		Message: fmt.Sprintf("%s are not supported by this edge store", feature),
+	})
+}
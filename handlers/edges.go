@@ -0,0 +1,370 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/sonnes/loki/database"
+	"github.com/sonnes/loki/models"
+)
+
+// The operations on edges shared by the HTTP & the gRPC APIs. They
+// authorize the key & limit the client of the context, and return
+// an *AppError or an error from the models, see ModelAppError.
+
+type InitEdgeRequest struct {
+	Name      string
+	Namespace *string
+	Counters  bool
+	Qualified bool
+	Symmetric bool
+	Inverse   string
+	IdType    string       `json:"id_type"`
+	SrcTypes  []string     `json:"src_types"`
+	DestTypes []string     `json:"dest_types"`
+	Schema    *models.Data `json:"schema"`
+}
+
+type EdgesListRequest struct {
+	Namespace *string
+	Edges     *[]models.Edge
+}
+
+// InitEdge registers the edge type of the request, along with its
+// inverse, and returns it.
+func InitEdge(ctx context.Context, store models.EdgeStore, request *InitEdgeRequest) (*models.EdgeType, error) {
+
+	if request.Name == "" {
+		return nil, &AppError{
+			Code:    http.StatusBadRequest,
+			Message: "Name is required to initialize the edge",
+			Fields:  &[]string{"name"},
+		}
+	}
+
+	name := request.Name
+
+	if request.Namespace != nil {
+
+		if *request.Namespace == "" || strings.Contains(*request.Namespace+name, ".") {
+			return nil, &AppError{
+				Code:    http.StatusBadRequest,
+				Message: "Namespace and name cannot be empty or contain `.`",
+				Fields:  &[]string{"namespace"},
+			}
+		}
+
+		name = database.QualifyName(*request.Namespace, name)
+	}
+
+	if !database.ValidTableName(name) {
+		return nil, &AppError{
+			Code:    http.StatusBadRequest,
+			Message: models.NAME_GRAMMAR_MESSAGE,
+			Fields:  &[]string{"name"},
+		}
+	}
+
+	if request.Schema != nil {
+
+		if _, err := models.CompileSchema(request.Schema); err != nil {
+			return nil, &AppError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Schema is not a valid JSON Schema: %v", err),
+				Fields:  &[]string{"schema"},
+			}
+		}
+	}
+
+	if _, ok := store.(models.CounterStore); request.Counters && !ok {
+		return nil, &AppError{
+			Code:    http.StatusBadRequest,
+			Message: "Counters are not supported by this edge store",
+			Fields:  &[]string{"counters"},
+		}
+	}
+
+	if err := Authorize(ctx, models.ADMIN, name); err != nil {
+		return nil, err
+	}
+
+	if _, ok := models.ID_COLUMN_TYPES[request.IdType]; !ok {
+		return nil, &AppError{
+			Code:    http.StatusBadRequest,
+			Message: "Id type must be one of int, string or uuid",
+			Fields:  &[]string{"id_type"},
+		}
+	}
+
+	// the node id columns of a table cannot change once it is created
+	existingType, err := store.GetType(name)
+
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	if existingType != nil && existingType.Options.IdColumnType() != models.ID_COLUMN_TYPES[request.IdType] {
+		return nil, &AppError{
+			Code:    http.StatusBadRequest,
+			Message: fmt.Sprintf("%s - edge already has %s node ids", name, existingType.Options.IdColumnType()),
+			Fields:  &[]string{"id_type"},
+		}
+	}
+
+	namespace, typeName := database.SplitName(name)
+
+	edgeType := &models.EdgeType{
+		Namespace: namespace,
+		Name:      typeName,
+		SrcTypes:  request.SrcTypes,
+		DestTypes: request.DestTypes,
+		Schema:    request.Schema,
+		Options: models.EdgeTypeOptions{
+			Counters:  request.Counters,
+			Qualified: request.Qualified,
+			IdType:    request.IdType,
+			Symmetric: request.Symmetric,
+			Inverse:   request.Inverse,
+		},
+	}
+
+	inverseType, err := models.InverseType(store, edgeType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if inverseType != nil {
+
+		if err := Authorize(ctx, models.ADMIN, inverseType.FullName()); err != nil {
+			return nil, err
+		}
+	}
+
+	err = store.CreateType(edgeType)
+
+	if err == nil && inverseType != nil {
+		err = store.CreateType(inverseType)
+	}
+
+	if err != nil {
+		return nil, internalError(err)
+	}
+
+	return edgeType, nil
+}
+
+// validateEdgeList checks every edge of a save or a delete has a
+// name & node ids, after the namespace of the request is applied.
+func validateEdgeList(request *EdgesListRequest) error {
+
+	if request.Edges == nil || len(*request.Edges) == 0 {
+		return &AppError{
+			Code:    http.StatusBadRequest,
+			Message: "There has to be atleast one edge to save",
+			Fields:  &[]string{"edges"},
+		}
+	}
+
+	models.ApplyNamespace(request.Namespace, request.Edges)
+
+	for idx, edge := range *request.Edges {
+
+		if edge.Name == nil {
+			return &AppError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Edge at %d does not have `name`", idx),
+				Fields:  &[]string{fmt.Sprintf("edges.%d.name", idx)},
+			}
+		}
+
+		if edge.SrcId == "" || edge.DestId == "" {
+			return &AppError{
+				Code:    http.StatusBadRequest,
+				Message: fmt.Sprintf("Edge at %d does not have `src_id` and `dest_id`", idx),
+				Fields:  &[]string{fmt.Sprintf("edges.%d.name", idx)},
+			}
+		}
+	}
+
+	return nil
+}
+
+// admitEdges checks the edges of a write are within the limits of
+// the request & the budget of the client, and that the key of the
+// context is allowed the action on them.
+func admitEdges(ctx context.Context, action string, edgesPtr *[]models.Edge) error {
+
+	if err := WithinEdgeLimit(ctx, len(*edgesPtr), "edges"); err != nil {
+		return err
+	}
+
+	if err := Authorize(ctx, action, edgeNames(edgesPtr)...); err != nil {
+		return err
+	}
+
+	return AllowEdges(ctx, len(*edgesPtr))
+}
+
+func SaveEdges(ctx context.Context, store models.EdgeStore, request *EdgesListRequest) ([]models.EdgeResult, error) {
+
+	if err := validateEdgeList(request); err != nil {
+		return nil, err
+	}
+
+	if err := admitEdges(ctx, models.WRITE, request.Edges); err != nil {
+		return nil, err
+	}
+
+	models.SetDefaults(request.Edges, time.Now())
+
+	if err := models.ValidateEdges(store, request.Edges); err != nil {
+		return nil, err
+	}
+
+	return store.Save(request.Edges)
+}
+
+func DeleteEdges(ctx context.Context, store models.EdgeStore, request *EdgesListRequest) ([]models.EdgeResult, error) {
+
+	if err := validateEdgeList(request); err != nil {
+		return nil, err
+	}
+
+	if err := admitEdges(ctx, models.DELETE, request.Edges); err != nil {
+		return nil, err
+	}
+
+	if err := models.ValidateDeletes(store, request.Edges); err != nil {
+		return nil, err
+	}
+
+	// deletes without `updated` happen as they are received
+	models.SetDefaults(request.Edges, time.Now())
+
+	return store.Delete(request.Edges)
+}
+
+// BatchEdges applies saves & deletes of edges of any edge types
+// atomically, in the order they are given.
+func BatchEdges(ctx context.Context, store models.EdgeStore, batch *models.Batch) ([][]models.EdgeResult, error) {
+
+	// writes without `updated` happen as they are received
+	batch.SetDefaults(time.Now())
+
+	if err := batch.Validate(store); err != nil {
+		return nil, err
+	}
+
+	edgeCount := 0
+
+	for _, operation := range batch.Operations {
+
+		action := models.WRITE
+
+		if operation.Action == models.DELETE {
+			action = models.DELETE
+		}
+
+		if err := Authorize(ctx, action, edgeNames(operation.Edges)...); err != nil {
+			return nil, err
+		}
+
+		edgeCount += len(*operation.Edges)
+	}
+
+	if err := WithinEdgeLimit(ctx, edgeCount, "operations"); err != nil {
+		return nil, err
+	}
+
+	if err := AllowEdges(ctx, edgeCount); err != nil {
+		return nil, err
+	}
+
+	return store.Apply(batch.Operations)
+}
+
+// QueryEdges lists a page of the edges matching the query, with the
+// cursor of the next page.
+func QueryEdges(ctx context.Context, store models.EdgeStore, query *models.EdgeQuery) (*[]models.Edge, string, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, "", ValidationAppError(err)
+	}
+
+	if err := Authorize(ctx, models.READ, *query.Name); err != nil {
+		return nil, "", err
+	}
+
+	if err := canonicalIds(store, *query.Name, query.SrcIds, query.DestIds); err != nil {
+		return nil, "", err
+	}
+
+	// unknown edges are 404s, see ModelAppError
+	edgeListPtr, cursor, err := store.List(query)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	return edgeListPtr, cursor, nil
+}
+
+func CountEdges(ctx context.Context, store models.EdgeStore, query *models.CountQuery) (map[models.NodeId]int64, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, ValidationAppError(err)
+	}
+
+	if err := Authorize(ctx, models.READ, *query.Name); err != nil {
+		return nil, err
+	}
+
+	if err := canonicalIds(store, *query.Name, query.SrcIds, query.DestIds); err != nil {
+		return nil, err
+	}
+
+	counts, err := store.Count(query)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return counts, nil
+}
+
+// canonicalIds puts the node ids of a query in the Canonical form of
+// the id type of the edge, which the results are keyed by.
+func canonicalIds(store models.EdgeStore, edgeName string, idLists ...[]models.NodeId) error {
+
+	edgeType, err := store.GetType(edgeName)
+
+	if err != nil {
+		return err
+	}
+
+	idType := ""
+
+	if edgeType != nil {
+		idType = edgeType.Options.IdType
+	}
+
+	for _, ids := range idLists {
+		models.CanonicalIds(ids, idType)
+	}
+
+	return nil
+}
+
+// internalError is a 500, for errors of the store that are not
+// the fault of the request.
+func internalError(err error) *AppError {
+
+	return &AppError{
+		Code:    http.StatusInternalServerError,
+		Message: err.Error(),
+	}
+}
@@ -4,218 +4,358 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
-	"time"
 
-	"github.com/jmoiron/sqlx"
-	"github.com/sonnes/loki/database"
+	"github.com/go-chi/chi"
 	"github.com/sonnes/loki/models"
 )
 
-type InitEdgeRequest struct {
-	Name      string
-	Namespace *string
+func InitEdgeEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	var jsonBody InitEdgeRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		WriteDecodeError(w, err)
+		return
+	}
+
+	defer r.Body.Close()
+
+	edgeType, err := InitEdge(r.Context(), store, &jsonBody)
+
+	if err != nil {
+		WriteModelError(w, err)
+		return
+	}
+
+	responseJson := make(map[string]string)
+
+	responseJson["success"] = "true"
+	responseJson["message"] = fmt.Sprintf("%s - edge has been created successfully:", edgeType.FullName())
+
+	WriteJson(w, responseJson, http.StatusOK)
 }
 
-func InitEdgeEndpoint(Db *sqlx.DB, w http.ResponseWriter, r *http.Request) {
+func SaveEdgesEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
 
-	var jsonBody InitEdgeRequest
+	var jsonBody EdgesListRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
-		WriteError(w, &AppError{
-			Code:    http.StatusBadRequest,
-			Message: err.Error(),
-		})
+		WriteDecodeError(w, err)
 		return
 	}
 
 	defer r.Body.Close()
 
-	if jsonBody.Name == "" {
-		WriteError(w, &AppError{
-			Code:    http.StatusBadRequest,
-			Message: "Name is required to initialize the edge",
-			Fields:  &[]string{"name"},
-		})
+	results, err := SaveEdges(r.Context(), store, &jsonBody)
+
+	if err != nil {
+		WriteModelError(w, err)
+		return
+	}
+
+	responseJson := make(map[string]interface{})
+
+	responseJson["success"] = "true"
+	responseJson["results"] = results
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
+
+func DeleteEdgesEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	var jsonBody EdgesListRequest
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		WriteDecodeError(w, err)
 		return
 	}
 
-	err := database.CreateTable(Db, jsonBody.Name)
+	defer r.Body.Close()
+
+	results, err := DeleteEdges(r.Context(), store, &jsonBody)
 
 	if err != nil {
-		WriteError(w, &AppError{
-			Code:    http.StatusInternalServerError,
-			Message: err.Error(),
-		})
+		WriteModelError(w, err)
 		return
 	}
 
-	err = database.CreateDefaultIndexes(Db, jsonBody.Name)
+	responseJson := make(map[string]interface{})
+
+	responseJson["success"] = "true"
+	responseJson["results"] = results
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
+
+// BatchEdgesEndpoint applies saves & deletes of edges of any edge
+// types atomically, in the order they are given.
+func BatchEdgesEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	var jsonBody models.Batch
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		WriteDecodeError(w, err)
+		return
+	}
+
+	defer r.Body.Close()
+
+	results, err := BatchEdges(r.Context(), store, &jsonBody)
 
 	if err != nil {
-		WriteError(w, &AppError{
-			Code:    http.StatusInternalServerError,
-			Message: err.Error(),
-		})
+		WriteModelError(w, err)
 		return
 	}
 
-	responseJson := make(map[string]string)
+	responseJson := make(map[string]interface{})
 
 	responseJson["success"] = "true"
-	responseJson["message"] = fmt.Sprintf("%s - edge has been created successfully:", jsonBody.Name)
+	responseJson["results"] = results
 
 	WriteJson(w, responseJson, http.StatusOK)
 }
 
-type EdgesListRequest struct {
-	Edges *[]models.Edge
+func RunQueryEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	var jsonBody models.EdgeQuery
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		WriteDecodeError(w, err)
+		return
+	}
+
+	defer r.Body.Close()
+
+	edgeListPtr, cursor, err := QueryEdges(r.Context(), store, &jsonBody)
+
+	if err != nil {
+		WriteModelError(w, err)
+		return
+	}
+
+	responseJson := make(map[string]interface{})
+
+	responseJson["success"] = "true"
+	responseJson["edges"] = *edgeListPtr
+	responseJson["cursor"] = nil
+
+	if cursor != "" {
+		responseJson["cursor"] = cursor
+	}
+
+	WriteJson(w, responseJson, http.StatusOK)
 }
 
-func SaveEdgesEndpoint(db *sqlx.DB, w http.ResponseWriter, r *http.Request) {
+func CountEdgesEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
 
-	var jsonBody EdgesListRequest
+	var jsonBody models.CountQuery
 
 	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
-		WriteError(w, &AppError{
-			Code:    http.StatusBadRequest,
-			Message: err.Error(),
-		})
+		WriteDecodeError(w, err)
 		return
 	}
 
 	defer r.Body.Close()
 
-	if len(*jsonBody.Edges) == 0 {
-		WriteError(w, &AppError{
-			Code:    http.StatusBadRequest,
-			Message: "There has to be atleast one edge to save",
-			Fields:  &[]string{"edges"},
-		})
+	counts, err := CountEdges(r.Context(), store, &jsonBody)
+
+	if err != nil {
+		WriteModelError(w, err)
 		return
 	}
 
-	// validate edges & add default values, if required
-	for idx, edge := range *jsonBody.Edges {
-		if edge.Name == nil {
-			WriteError(w, &AppError{
-				Code:    http.StatusBadRequest,
-				Message: fmt.Sprintf("Edge at %d does not have `name`", idx),
-				Fields:  &[]string{fmt.Sprintf("edges.%d.name", idx)},
-			})
-			return
-		}
+	responseJson := make(map[string]interface{})
 
-		if edge.SrcId == 0 || edge.DestId == 0 {
-			WriteError(w, &AppError{
-				Code:    http.StatusBadRequest,
-				Message: fmt.Sprintf("Edge at %d does not have `src_id` and `dest_id`", idx),
-				Fields:  &[]string{fmt.Sprintf("edges.%d.name", idx)},
-			})
+	responseJson["success"] = "true"
+	responseJson["counts"] = counts
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
+
+func CombineSetsEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	var jsonBody models.SetQuery
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		WriteDecodeError(w, err)
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := jsonBody.Validate(); err != nil {
+		WriteValidationError(w, err)
+		return
+	}
+
+	for _, set := range jsonBody.Sets {
+		if !Authorized(w, r, models.READ, *set.Name) {
 			return
 		}
 	}
 
-	saveErr := models.SaveMany(db, jsonBody.Edges)
+	combiner, ok := store.(models.SetCombiner)
 
-	if saveErr != nil {
-		WriteError(w, &AppError{
-			Code:    http.StatusInternalServerError,
-			Message: saveErr.Error(),
-		})
+	if !ok {
+		WriteUnsupported(w, "Set operations")
 		return
 	}
 
-	responseJson := make(map[string]string)
+	result, queryErr := combiner.CombineSets(&jsonBody)
+
+	if queryErr != nil {
+		WriteModelError(w, queryErr)
+		return
+	}
+
+	responseJson := make(map[string]interface{})
 
 	responseJson["success"] = "true"
 
+	if result.Count != nil {
+		responseJson["count"] = *result.Count
+	} else {
+		responseJson["node_ids"] = result.NodeIds
+		responseJson["edges"] = result.Edges
+	}
+
 	WriteJson(w, responseJson, http.StatusOK)
 }
 
-func DeleteEdgesEndpoint(db *sqlx.DB, w http.ResponseWriter, r *http.Request) {
+func TraverseEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
 
-	var jsonBody EdgesListRequest
+	var jsonBody models.Traversal
 
 	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
-		WriteError(w, &AppError{
-			Code:    http.StatusBadRequest,
-			Message: err.Error(),
-		})
+		WriteDecodeError(w, err)
 		return
 	}
 
 	defer r.Body.Close()
 
-	if len(*jsonBody.Edges) == 0 {
+	if err := jsonBody.Validate(); err != nil {
+		WriteValidationError(w, err)
+		return
+	}
+
+	for _, hop := range jsonBody.Hops {
+		if !Authorized(w, r, models.READ, *hop.Name) {
+			return
+		}
+	}
+
+	traverser, ok := store.(models.Traverser)
+
+	if !ok {
+		WriteUnsupported(w, "Traversals")
+		return
+	}
+
+	nodesPtr, traverseErr := traverser.Traverse(&jsonBody)
+
+	if traverseErr != nil {
+		WriteModelError(w, traverseErr)
+		return
+	}
+
+	responseJson := make(map[string]interface{})
+
+	responseJson["success"] = "true"
+	responseJson["nodes"] = *nodesPtr
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
+
+func ListEdgeTypesEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	edgeTypesPtr, err := store.ListTypes()
+
+	if err != nil {
 		WriteError(w, &AppError{
-			Code:    http.StatusBadRequest,
-			Message: "There has to be atleast one edge to save",
-			Fields:  &[]string{"edges"},
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
 		})
 		return
 	}
 
-	// validate edges & add default values
-	for idx, edge := range *jsonBody.Edges {
+	edgeTypes := make([]models.EdgeType, 0, len(*edgeTypesPtr))
 
-		if edge.Status == "" {
-			edge.Status = models.ACTIVE
+	// keys only list the edge types they can read
+	for _, edgeType := range *edgeTypesPtr {
+		if key := RequestKey(r); key == nil || key.Allows(models.READ, edgeType.FullName()) {
+			edgeTypes = append(edgeTypes, edgeType)
 		}
+	}
 
-		if edge.Updated == nil {
-			updated := time.Now()
-			edge.Updated = &updated
-		}
+	for idx := range edgeTypes {
 
-		if edge.Name == nil {
+		if err := store.LoadStats(&edgeTypes[idx]); err != nil {
 			WriteError(w, &AppError{
-				Code:    http.StatusBadRequest,
-				Message: fmt.Sprintf("Edge at %d does not have `name`", idx),
-				Fields:  &[]string{fmt.Sprintf("edges.%d.name", idx)},
+				Code:    http.StatusInternalServerError,
+				Message: err.Error(),
 			})
 			return
 		}
+	}
 
-		if edge.SrcId == 0 || edge.DestId == 0 {
-			WriteError(w, &AppError{
-				Code:    http.StatusBadRequest,
-				Message: fmt.Sprintf("Edge at %d does not have `src_id` and `dest_id`", idx),
-				Fields:  &[]string{fmt.Sprintf("edges.%d.name", idx)},
-			})
-			return
-		}
+	responseJson := make(map[string]interface{})
+
+	responseJson["success"] = "true"
+	responseJson["types"] = edgeTypes
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
+
+func GetEdgeTypeEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	edgeName := chi.URLParam(r, "name")
+
+	if !Authorized(w, r, models.READ, edgeName) {
+		return
 	}
 
-	saveErr := models.DeleteMany(db, jsonBody.Edges)
+	edgeType, err := store.GetType(edgeName)
 
-	if saveErr != nil {
+	if err == nil && edgeType != nil {
+		err = store.LoadStats(edgeType)
+	}
+
+	if err != nil {
 		WriteError(w, &AppError{
 			Code:    http.StatusInternalServerError,
-			Message: saveErr.Error(),
+			Message: err.Error(),
 		})
 		return
 	}
 
-	responseJson := make(map[string]string)
+	if edgeType == nil {
+		WriteError(w, &AppError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("%s - edge type does not exist", edgeName),
+		})
+		return
+	}
+
+	responseJson := make(map[string]interface{})
 
 	responseJson["success"] = "true"
+	responseJson["type"] = edgeType
 
 	WriteJson(w, responseJson, http.StatusOK)
 }
 
-type QueryRequest struct {
+type SQLRequest struct {
 	Query string `json:"query"`
 }
 
-func RunQueryEndpoint(db *sqlx.DB, w http.ResponseWriter, r *http.Request) {
+// RunSQLEndpoint executes a raw SQL statement. It is only routed
+// when RAW_SQL_ENABLED is set, apps should use `/v1/edges/query`.
+func RunSQLEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
 
-	var jsonBody QueryRequest
+	var jsonBody SQLRequest
 
 	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
-		WriteError(w, &AppError{
-			Code:    http.StatusBadRequest,
-			Message: err.Error(),
-		})
+		WriteDecodeError(w, err)
 		return
 	}
 
@@ -230,7 +370,18 @@ func RunQueryEndpoint(db *sqlx.DB, w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	edgeListPtr, queryErr := models.RunQuery(db, jsonBody.Query)
+	if !AuthorizedAdmin(w, r) {
+		return
+	}
+
+	runner, ok := store.(models.SQLRunner)
+
+	if !ok {
+		WriteUnsupported(w, "Raw SQL")
+		return
+	}
+
+	edgeListPtr, queryErr := runner.RunQuery(jsonBody.Query)
 
 	if queryErr != nil {
 		WriteError(w, &AppError{
@@ -9,7 +9,6 @@ import (
 	"time"
 
 	"cloud.google.com/go/pubsub"
-	"github.com/jmoiron/sqlx"
 	"github.com/sonnes/loki/models"
 )
 
@@ -19,12 +18,13 @@ const (
 
 type PubsubMessage struct {
 	Action    string         `json:"action"`
+	Namespace *string        `json:"namespace"`
 	Edges     *[]models.Edge `json:"edges"`
 	Payload   *[]models.Edge `json:"payload"`
 	Timestamp *time.Time     `json:"timestamp"`
 }
 
-func StartPubsubListen(db *sqlx.DB) error {
+func StartPubsubListen(store models.EdgeStore) error {
 	ctx := context.Background()
 
 	topicName := os.Getenv("SYNC_PUBSUB_TOPIC_NAME")
@@ -49,6 +49,20 @@ func StartPubsubListen(db *sqlx.DB) error {
 
 	log.Printf("Intialized topic: %v", topic)
 
+	// invalid edges are published to the dead-letter topic, if any
+	var deadLetterTopic *pubsub.Topic
+
+	if deadLetterTopicName := os.Getenv("DEADLETTER_PUBSUB_TOPIC_NAME"); deadLetterTopicName != "" {
+
+		deadLetterTopic, err = createTopicIfNotExists(client, deadLetterTopicName)
+
+		if err != nil {
+			return err
+		}
+
+		log.Printf("Intialized dead-letter topic: %v", deadLetterTopic)
+	}
+
 	// create a subscrption
 	subscription, err := createSubIfNotExists(client, topic, "edgestore.edges.subscription")
 
@@ -77,27 +91,44 @@ func StartPubsubListen(db *sqlx.DB) error {
 			return
 		}
 
-		// validate edges & add default values
-		for _, edge := range *message.Edges {
+		models.ApplyNamespace(message.Namespace, message.Edges)
 
-			if edge.Status == "" {
-				edge.Status = models.ACTIVE
-			}
+		// writes without `updated` happen when they are published
+		updated := m.PublishTime
 
-			if edge.Updated == nil && message.Timestamp != nil {
-				edge.Updated = message.Timestamp
-			} else if edge.Updated == nil {
-				curr := time.Now()
-				edge.Updated = &curr
-			}
-
-			// TODO how to validate and deal with message ack/no-ack
+		if message.Timestamp != nil {
+			updated = *message.Timestamp
 		}
 
+		models.SetDefaults(message.Edges, updated)
+
 		if message.Action == "/edges/save" {
-			err = models.SaveMany(db, message.Edges)
+			err = models.ValidateEdges(store, message.Edges)
 		} else if message.Action == "/edges/delete" {
-			err = models.DeleteMany(db, message.Edges)
+			err = models.ValidateDeletes(store, message.Edges)
+		}
+
+		if models.IsValidationError(err) {
+			deadLetter(ctx, deadLetterTopic, m, err)
+			return
+		}
+
+		if err == nil && message.Action == "/edges/save" {
+			_, err = store.Save(message.Edges)
+		} else if err == nil && message.Action == "/edges/delete" {
+			_, err = store.Delete(message.Edges)
+		}
+
+		// retrying the write would fail the same preconditions
+		if _, ok := err.(*models.PreconditionError); ok {
+			deadLetter(ctx, deadLetterTopic, m, err)
+			return
+		}
+
+		// or write to the same missing edge table
+		if _, ok := err.(*models.UnknownEdgeError); ok {
+			deadLetter(ctx, deadLetterTopic, m, err)
+			return
 		}
 
 		if err != nil {
@@ -118,6 +149,33 @@ func StartPubsubListen(db *sqlx.DB) error {
 	return nil
 }
 
+// deadLetter acks a message with invalid edges, after publishing it
+// to the dead-letter topic with the validation error.
+func deadLetter(ctx context.Context, topic *pubsub.Topic, m *pubsub.Message, validationErr error) {
+
+	if topic == nil {
+		log.Printf("Invalid edges in message. Ignoring & Acking. Error: %v", validationErr)
+		m.Ack()
+		return
+	}
+
+	result := topic.Publish(ctx, &pubsub.Message{
+		Data: m.Data,
+		Attributes: map[string]string{
+			"error": validationErr.Error(),
+		},
+	})
+
+	if _, err := result.Get(ctx); err != nil {
+		log.Printf("Could not dead-letter message with invalid edges %v", err)
+		m.Nack()
+		return
+	}
+
+	log.Printf("Invalid edges in message. Dead-lettered & Acking. Error: %v", validationErr)
+	m.Ack()
+}
+
 func createTopicIfNotExists(client *pubsub.Client, topicName string) (*pubsub.Topic, error) {
 
 	ctx := context.Background()
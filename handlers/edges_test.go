@@ -2,30 +2,100 @@ package handlers
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
 	"log"
 	"net/http"
 	"net/http/httptest"
+	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/sonnes/loki/database"
 	"github.com/sonnes/loki/models"
 )
 
+// The handler tests run against a MemoryStore, or against Postgres
+// when TEST_POSTGRES_CONNECTION is set. Features only Postgres has
+// are skipped without it.
 const (
-	LOCAL_DB_URL string = "user=raviatluri password=psqlArmStrong5223 dbname=edgestore host=localhost sslmode=disable"
+	TEST_POSTGRES_ENV string = "TEST_POSTGRES_CONNECTION"
 )
 
-func TestHealthCHeck(t *testing.T) {
+func TestMain(m *testing.M) {
+
+	if connection := os.Getenv(TEST_POSTGRES_ENV); connection != "" {
+
+		Db := database.InitDB(connection)
+
+		if err := database.CreateSystemTables(Db); err != nil {
+			log.Printf("could not create system tables: %v", err)
+		}
+
+		Db.Close()
+	}
+
+	os.Exit(m.Run())
+}
+
+// testStore returns a store with the edge tables, which are dropped
+// when the test ends.
+func testStore(t *testing.T, edgeNames ...string) models.EdgeStore {
+
+	if os.Getenv(TEST_POSTGRES_ENV) != "" {
+		return postgresStore(t, edgeNames...)
+	}
+
+	store := models.NewMemoryStore()
+
+	for _, edgeName := range edgeNames {
+		namespace, name := database.SplitName(edgeName)
+
+		_ = store.CreateType(&models.EdgeType{Namespace: namespace, Name: name})
+	}
+
+	return store
+}
+
+func postgresStore(t *testing.T, edgeNames ...string) *models.PostgresStore {
+
+	connection := os.Getenv(TEST_POSTGRES_ENV)
+
+	if connection == "" {
+		t.Skipf("%s is not set", TEST_POSTGRES_ENV)
+	}
+
+	store := models.NewPostgresStore(database.InitDB(connection))
+
+	for _, edgeName := range edgeNames {
+		_ = database.CreateTable(store.Db, edgeName, "bigint")
+	}
 
-	Db := database.InitDB(LOCAL_DB_URL)
+	// defer cleanup
+	t.Cleanup(func() {
+
+		for _, edgeName := range edgeNames {
+			namespace, name := database.SplitName(edgeName)
+
+			database.DropTable(store.Db, edgeName)
+			store.Db.Exec("DELETE FROM loki_edge_types WHERE namespace = $1 AND name = $2", namespace, name)
+		}
+
+		store.Close()
+	})
+
+	return store
+}
+
+func TestHealthCHeck(t *testing.T) {
 
-	defer Db.Close()
+	store := testStore(t)
 
 	req := httptest.NewRequest("GET", "/_ah/health", nil)
 
 	res := httptest.NewRecorder()
-	handler := CreateRouter(Db)
+	handler := CreateRouter(store, &RateLimits{})
 
 	handler.ServeHTTP(res, req)
 
@@ -37,28 +107,29 @@ func TestHealthCHeck(t *testing.T) {
 
 func TestInitEdgeEndpoint(t *testing.T) {
 
-	Db := database.InitDB(LOCAL_DB_URL)
-
-	defer Db.Close()
-
 	testTableName := "test_create_edge_2"
 
+	store := testStore(t)
+
 	postBody := fmt.Sprintf(`
     {
       "name": "%s"
     }
   `, testTableName)
 
-	// defer cleanup
-	defer func() {
-		database.DropTable(Db, testTableName)
-	}()
+	if pgStore, ok := store.(*models.PostgresStore); ok {
+		// defer cleanup
+		defer func() {
+			database.DropTable(pgStore.Db, testTableName)
+			pgStore.Db.Exec("DELETE FROM loki_edge_types WHERE name = $1", testTableName)
+		}()
+	}
 
 	req := httptest.NewRequest("POST", "/v1/edges/init", bytes.NewReader([]byte(postBody)))
 	req.Header.Add("Content-Type", "application/json")
 
 	res := httptest.NewRecorder()
-	handler := CreateRouter(Db)
+	handler := CreateRouter(store, &RateLimits{})
 
 	handler.ServeHTTP(res, req)
 
@@ -70,11 +141,107 @@ func TestInitEdgeEndpoint(t *testing.T) {
 	}
 }
 
-func TestInitEdgeEndpoint_NameValidation(t *testing.T) {
+func TestInitEdgeEndpoint_Counters(t *testing.T) {
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	// counters are only kept in Postgres
+	for _, store := range []models.EdgeStore{models.NewMemoryStore(), sqliteStore} {
+
+		req := httptest.NewRequest("POST", "/v1/edges/init", bytes.NewReader([]byte(`{ "name" : "test_counted_edges", "counters" : true }`)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+		handler := CreateRouter(store, &RateLimits{})
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusBadRequest {
+			t.Errorf("handler returned wrong status code: got %v want %v",
+				status, http.StatusBadRequest)
+		}
+
+		if edgeType, _ := store.GetType("test_counted_edges"); edgeType != nil {
+			t.Errorf("edge type with counters was created: %v", edgeType)
+		}
+	}
+}
+
+func TestInitEdgeEndpoint_Namespace(t *testing.T) {
+
+	testNamespace := "test_app"
+	testTableName := "test_namespaced_edges"
+
+	store := testStore(t)
+
+	if pgStore, ok := store.(*models.PostgresStore); ok {
+		// defer cleanup
+		defer func() {
+			pgStore.Db.Exec("DROP SCHEMA IF EXISTS " + testNamespace + " CASCADE")
+			pgStore.Db.Exec("DELETE FROM loki_edge_types WHERE namespace = $1", testNamespace)
+		}()
+	}
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	postBody := fmt.Sprintf(`
+    {
+      "name": "%s",
+      "namespace": "%s"
+    }
+  `, testTableName, testNamespace)
+
+	req := httptest.NewRequest("POST", "/v1/edges/init", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	postBody = fmt.Sprintf(`
+    {
+      "namespace" : "%s",
+      "edges" : [
+        { "name" : "%s", "src_id" : 1, "dest_id" : 2, "status" : "active" }
+      ]
+    }
+  `, testNamespace, testTableName)
+
+	req = httptest.NewRequest("POST", "/v1/edges/save", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res = httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	qualifiedName := testNamespace + "." + testTableName
+
+	edgesPtr, _, err := store.List(&models.EdgeQuery{Name: &qualifiedName})
+
+	if err != nil || len(*edgesPtr) != 1 {
+		t.Errorf("could not find the edge saved in the namespace: %v", err)
+	}
+}
 
-	Db := database.InitDB(LOCAL_DB_URL)
+func TestInitEdgeEndpoint_NameValidation(t *testing.T) {
 
-	defer Db.Close()
+	store := testStore(t)
 
 	postBody := "{}"
 
@@ -82,7 +249,7 @@ func TestInitEdgeEndpoint_NameValidation(t *testing.T) {
 	req.Header.Add("Content-Type", "application/json")
 
 	res := httptest.NewRecorder()
-	handler := CreateRouter(Db)
+	handler := CreateRouter(store, &RateLimits{})
 
 	handler.ServeHTTP(res, req)
 
@@ -92,20 +259,120 @@ func TestInitEdgeEndpoint_NameValidation(t *testing.T) {
 	}
 }
 
-func TestSaveEdgesEndpoint(t *testing.T) {
+func TestInitEdgeEndpoint_NameGrammar(t *testing.T) {
 
-	Db := database.InitDB(LOCAL_DB_URL)
+	store := testStore(t)
 
-	defer Db.Close()
+	handler := CreateRouter(store, &RateLimits{})
 
-	testTableName := "test_save_edges"
+	names := []string{
+		`{ "name" : "test_edges; DROP TABLE loki_edge_types" }`,
+		`{ "name" : "test\"edges" }`,
+		`{ "name" : "TestEdges" }`,
+		`{ "name" : "1test_edges" }`,
+		`{ "name" : "test_edges", "namespace" : "test-app" }`,
+		`{ "name" : "test_edges", "inverse" : "test edges" }`,
+		`{ "name" : "test_likes_counts" }`,
+		`{ "name" : "loki_api_keys" }`,
+		`{ "name" : "feed_home_inbox", "namespace" : "test_app" }`,
+		fmt.Sprintf(`{ "name" : "%s" }`, strings.Repeat("a", 41)),
+	}
 
-	_ = database.CreateTable(Db, testTableName)
+	for _, body := range names {
 
-	// defer cleanup
-	defer func() {
-		database.DropTable(Db, testTableName)
-	}()
+		req := httptest.NewRequest("POST", "/v1/edges/init", bytes.NewReader([]byte(body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusBadRequest {
+			t.Errorf("%s returned wrong status code: got %v want %v",
+				body, status, http.StatusBadRequest)
+		}
+	}
+}
+
+func TestSaveEdgesEndpoint_UnknownEdge(t *testing.T) {
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	for _, store := range []models.EdgeStore{testStore(t), sqliteStore} {
+
+		handler := CreateRouter(store, &RateLimits{})
+
+		for _, path := range []string{"/v1/edges/save", "/v1/edges/delete"} {
+
+			// tables of loki & of the database are not edges
+			for _, edgeName := range []string{"test_missing_edges", "loki_edge_types", "loki_api_keys", "feed_home_inbox", "sqlite_master"} {
+
+				body := fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2 } ] }`, edgeName)
+
+				req := httptest.NewRequest("POST", path, bytes.NewReader([]byte(body)))
+				req.Header.Add("Content-Type", "application/json")
+
+				res := httptest.NewRecorder()
+
+				handler.ServeHTTP(res, req)
+
+				if status := res.Code; status != http.StatusNotFound {
+					t.Errorf("%s of %s returned wrong status code: got %v want %v",
+						path, edgeName, status, http.StatusNotFound)
+				}
+			}
+		}
+	}
+}
+
+func TestQueryEdgesEndpoint_UnknownEdge(t *testing.T) {
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	// testStore is Postgres when it is configured
+	for _, store := range []models.EdgeStore{testStore(t), sqliteStore} {
+
+		handler := CreateRouter(store, &RateLimits{})
+
+		for _, path := range []string{"/v1/edges/query", "/v1/edges/count"} {
+
+			for _, edgeName := range []string{"test_missing_edges", "loki_edge_types", "sqlite_master"} {
+
+				body := fmt.Sprintf(`{ "name" : "%s", "src_id" : [ 1 ] }`, edgeName)
+
+				req := httptest.NewRequest("POST", path, bytes.NewReader([]byte(body)))
+				req.Header.Add("Content-Type", "application/json")
+
+				res := httptest.NewRecorder()
+
+				handler.ServeHTTP(res, req)
+
+				if status := res.Code; status != http.StatusNotFound {
+					t.Errorf("%s of %s returned wrong status code: got %v want %v",
+						path, edgeName, status, http.StatusNotFound)
+				}
+			}
+		}
+	}
+}
+
+func TestSaveEdgesEndpoint(t *testing.T) {
+
+	testTableName := "test_save_edges"
+
+	store := testStore(t, testTableName)
 
 	postBody := fmt.Sprintf(`
     {
@@ -136,7 +403,7 @@ func TestSaveEdgesEndpoint(t *testing.T) {
 	req.Header.Add("Content-Type", "application/json")
 
 	res := httptest.NewRecorder()
-	handler := CreateRouter(Db)
+	handler := CreateRouter(store, &RateLimits{})
 
 	handler.ServeHTTP(res, req)
 
@@ -148,34 +415,25 @@ func TestSaveEdgesEndpoint(t *testing.T) {
 
 func TestDeleteEdgesEndpoint(t *testing.T) {
 
-	Db := database.InitDB(LOCAL_DB_URL)
-
-	defer Db.Close()
-
 	testTableName := "test_delete_edges"
 
-	_ = database.CreateTable(Db, testTableName)
-
-	// defer cleanup
-	defer func() {
-		database.DropTable(Db, testTableName)
-	}()
+	store := testStore(t, testTableName)
 
 	edgesList := make([]models.Edge, 2)
 
 	edgesList[0] = models.Edge{
-		Name:   testTableName,
-		SrcId:  1,
-		DestId: 2,
+		Name:   &testTableName,
+		SrcId:  "1",
+		DestId: "2",
 	}
 
 	edgesList[1] = models.Edge{
-		Name:   testTableName,
-		SrcId:  3,
-		DestId: 4,
+		Name:   &testTableName,
+		SrcId:  "3",
+		DestId: "4",
 	}
 
-	_ = models.SaveMany(Db, &edgesList)
+	_, _ = store.Save(&edgesList)
 
 	postBody := fmt.Sprintf(`
     {
@@ -198,7 +456,7 @@ func TestDeleteEdgesEndpoint(t *testing.T) {
 	req.Header.Add("Content-Type", "application/json")
 
 	res := httptest.NewRecorder()
-	handler := CreateRouter(Db)
+	handler := CreateRouter(store, &RateLimits{})
 
 	handler.ServeHTTP(res, req)
 
@@ -210,13 +468,134 @@ func TestDeleteEdgesEndpoint(t *testing.T) {
 
 func TestRunQueryEndpoint(t *testing.T) {
 
-	Db := database.InitDB(LOCAL_DB_URL)
+	testTableName := "test_query_edges"
+
+	store := testStore(t, testTableName)
+
+	edgesList := []models.Edge{
+		{Name: &testTableName, SrcId: "1", DestId: "2", Score: 1, Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "1", DestId: "3", Score: 2, Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "2", DestId: "3", Score: 3, Status: models.ACTIVE},
+	}
+
+	_, _ = store.Save(&edgesList)
+
+	postBody := fmt.Sprintf(`
+    {
+      "name" : "%s",
+      "src_id" : [1],
+      "order_by" : "-score",
+      "limit" : 10
+    }
+  `, testTableName)
+
+	req := httptest.NewRequest("POST", "/v1/edges/query", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var response struct {
+		Edges []models.Edge `json:"edges"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if len(response.Edges) != 2 || response.Edges[0].DestId != "3" {
+		t.Errorf("handler returned unexpected edges: %v", response.Edges)
+	}
+}
+
+func TestRunQueryEndpoint_Paging(t *testing.T) {
+
+	testTableName := "test_page_edges"
+
+	store := testStore(t, testTableName)
+
+	edgesList := make([]models.Edge, 5)
+
+	for idx := range edgesList {
+		edgesList[idx] = models.Edge{
+			Name:   &testTableName,
+			SrcId:  "1",
+			DestId: models.NodeId(fmt.Sprint(idx + 2)),
+			Score:  1,
+			Status: models.ACTIVE,
+		}
+	}
+
+	_, _ = store.Save(&edgesList)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	seen := make(map[models.NodeId]bool)
+	cursor := ""
+
+	for page := 0; page < 3; page++ {
+
+		postBody := fmt.Sprintf(`
+      {
+        "name" : "%s",
+        "src_id" : [1],
+        "order_by" : "-score",
+        "limit" : 2,
+        "cursor" : "%s"
+      }
+    `, testTableName, cursor)
+
+		req := httptest.NewRequest("POST", "/v1/edges/query", bytes.NewReader([]byte(postBody)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v",
+				status, http.StatusOK)
+		}
+
+		var response struct {
+			Edges  []models.Edge `json:"edges"`
+			Cursor *string       `json:"cursor"`
+		}
+
+		_ = json.NewDecoder(res.Body).Decode(&response)
+
+		for _, edge := range response.Edges {
+			if seen[edge.DestId] {
+				t.Errorf("edge %s returned on more than one page", edge.DbId())
+			}
+			seen[edge.DestId] = true
+		}
+
+		if response.Cursor == nil {
+			break
+		}
+
+		cursor = *response.Cursor
+	}
+
+	if len(seen) != len(edgesList) {
+		t.Errorf("pages returned %d edges, want %d", len(seen), len(edgesList))
+	}
+}
+
+func TestRunQueryEndpoint_OrderValidation(t *testing.T) {
 
-	defer Db.Close()
+	store := testStore(t)
 
 	postBody := `
     {
-      "query" : "SELECT 1 as src_id, 2 as dest_id, '' as src_type, '' as dest_type, 0 as score, '1' as id, 'test_edge' as name, 'active' as status, now() as updated"
+      "name" : "test_edge",
+      "order_by" : "data; DROP TABLE test_edge"
     }
   `
 
@@ -224,7 +603,43 @@ func TestRunQueryEndpoint(t *testing.T) {
 	req.Header.Add("Content-Type", "application/json")
 
 	res := httptest.NewRecorder()
-	handler := CreateRouter(Db)
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+}
+
+func TestCountEdgesEndpoint(t *testing.T) {
+
+	testTableName := "test_count_edges"
+
+	store := testStore(t, testTableName)
+
+	edgesList := []models.Edge{
+		{Name: &testTableName, SrcId: "1", DestId: "3", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "2", DestId: "3", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "4", DestId: "3", Status: models.DELETED},
+		{Name: &testTableName, SrcId: "1", DestId: "2", Status: models.ACTIVE},
+	}
+
+	_, _ = store.Save(&edgesList)
+
+	postBody := fmt.Sprintf(`
+    {
+      "name" : "%s",
+      "dest_id" : [2, 3, 5]
+    }
+  `, testTableName)
+
+	req := httptest.NewRequest("POST", "/v1/edges/count", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
 
 	handler.ServeHTTP(res, req)
 
@@ -232,4 +647,1764 @@ func TestRunQueryEndpoint(t *testing.T) {
 		t.Errorf("handler returned wrong status code: got %v want %v",
 			status, http.StatusOK)
 	}
+
+	var response struct {
+		Counts map[int64]int64 `json:"counts"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if response.Counts[2] != 1 || response.Counts[3] != 2 || response.Counts[5] != 0 {
+		t.Errorf("handler returned unexpected counts: %v", response.Counts)
+	}
+}
+
+// countWrites saves edges to node 3, saves them again, saves a stale
+// tombstone of one & deletes the other, and counts the edges to 3,
+// which are not moved by the repeated & stale writes.
+func countWrites(t *testing.T, store models.EdgeStore, edgeName string) int64 {
+
+	earlier := time.Now().Add(-time.Hour)
+	later := time.Now()
+
+	saves := []models.Edge{
+		{Name: &edgeName, SrcId: "1", DestId: "3", Status: models.ACTIVE, Updated: &later},
+		{Name: &edgeName, SrcId: "2", DestId: "3", Status: models.ACTIVE, Updated: &later},
+	}
+
+	_, _ = store.Save(&saves)
+
+	// saving again & saving stale edges must not move the counts
+	_, _ = store.Save(&saves)
+
+	stale := []models.Edge{
+		{Name: &edgeName, SrcId: "1", DestId: "3", Status: models.DELETED, Updated: &earlier},
+	}
+
+	_, _ = store.Save(&stale)
+
+	deletes := []models.Edge{
+		{Name: &edgeName, SrcId: "2", DestId: "3", Updated: &later},
+	}
+
+	_, _ = store.Delete(&deletes)
+
+	counts, err := store.Count(&models.CountQuery{
+		Name:    &edgeName,
+		DestIds: []models.NodeId{"3"},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return counts["3"]
+}
+
+func TestCountEdgesEndpoint_Writes(t *testing.T) {
+
+	testTableName := "test_counted_edges"
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	_ = sqliteStore.CreateType(&models.EdgeType{Name: testTableName})
+
+	for _, store := range []models.EdgeStore{testStore(t, testTableName), sqliteStore} {
+
+		if count := countWrites(t, store, testTableName); count != 1 {
+			t.Errorf("%T returned %d edges, want %d", store, count, 1)
+		}
+	}
+}
+
+func TestCountEdgesEndpoint_Counters(t *testing.T) {
+
+	testTableName := "test_counter_edges"
+
+	store := postgresStore(t, testTableName)
+
+	_ = database.CreateCountsTable(store.Db, testTableName, "bigint")
+
+	if count := countWrites(t, store, testTableName); count != 1 {
+		t.Errorf("counters returned %d edges, want %d", count, 1)
+	}
+
+	_ = models.RebuildCounts(store.Db, testTableName)
+
+	counts, _ := store.Count(&models.CountQuery{
+		Name:   &testTableName,
+		SrcIds: []models.NodeId{"1", "2"},
+	})
+
+	if counts["1"] != 1 || counts["2"] != 0 {
+		t.Errorf("rebuilt counters returned unexpected counts: %v", counts)
+	}
+
+	// edge types registered without counters are counted with count(*)
+	_ = models.RegisterEdgeType(store.Db, &models.EdgeType{Name: testTableName})
+	_, _ = store.Db.Exec(fmt.Sprintf("UPDATE %s SET count = 99", database.QuoteName(database.CountsTableName(testTableName))))
+
+	counts, _ = store.Count(&models.CountQuery{
+		Name:   &testTableName,
+		SrcIds: []models.NodeId{"1"},
+	})
+
+	if counts["1"] != 1 {
+		t.Errorf("edge type without counters was counted from its counts table: %v", counts)
+	}
+}
+
+func TestCombineSetsEndpoint(t *testing.T) {
+
+	testTableName := "test_set_edges"
+
+	store := testStore(t, testTableName)
+
+	// 1 & 2 both follow 3 & 4, only 1 follows 5
+	edgesList := []models.Edge{
+		{Name: &testTableName, SrcId: "1", DestId: "3", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "1", DestId: "4", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "1", DestId: "5", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "2", DestId: "3", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "2", DestId: "4", Status: models.ACTIVE},
+	}
+
+	_, _ = store.Save(&edgesList)
+
+	postBody := fmt.Sprintf(`
+    {
+      "op" : "intersect",
+      "sets" : [
+        { "name" : "%[1]s", "node_id" : 1, "direction" : "out" },
+        { "name" : "%[1]s", "node_id" : 2, "direction" : "out" }
+      ]
+    }
+  `, testTableName)
+
+	req := httptest.NewRequest("POST", "/v1/edges/sets", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var response struct {
+		NodeIds []int64       `json:"node_ids"`
+		Edges   []models.Edge `json:"edges"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if len(response.NodeIds) != 2 || len(response.Edges) != 4 {
+		t.Errorf("handler returned unexpected nodes: %v", response.NodeIds)
+	}
+
+	for op, nodeIds := range map[string]string{"difference": "[5]", "union": "[3 4 5]"} {
+
+		postBody = fmt.Sprintf(`
+    {
+      "op" : "%[2]s",
+      "sets" : [
+        { "name" : "%[1]s", "node_id" : 1, "direction" : "out" },
+        { "name" : "%[1]s", "node_id" : 2, "direction" : "out" }
+      ]
+    }
+  `, testTableName, op)
+
+		req = httptest.NewRequest("POST", "/v1/edges/sets", bytes.NewReader([]byte(postBody)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res = httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		response.NodeIds = nil
+
+		_ = json.NewDecoder(res.Body).Decode(&response)
+
+		if fmt.Sprint(response.NodeIds) != nodeIds {
+			t.Errorf("%s returned unexpected nodes: got %v want %s", op, response.NodeIds, nodeIds)
+		}
+	}
+
+	// node ids are matched in their canonical form, unknown edges
+	// and ids of another type are client errors
+	for _, tc := range []struct {
+		sets string
+		code int
+	}{
+		{fmt.Sprintf(`{ "name" : "%[1]s", "node_id" : "01", "direction" : "out" }, { "name" : "%[1]s", "node_id" : "+2", "direction" : "out" }`, testTableName), http.StatusOK},
+		{fmt.Sprintf(`{ "name" : "%[1]s", "node_id" : 1, "direction" : "out" }, { "name" : "test_missing_edges", "node_id" : 2, "direction" : "out" }`, testTableName), http.StatusNotFound},
+		{fmt.Sprintf(`{ "name" : "%[1]s", "node_id" : "one", "direction" : "out" }, { "name" : "%[1]s", "node_id" : 2, "direction" : "out" }`, testTableName), http.StatusBadRequest},
+	} {
+
+		req = httptest.NewRequest("POST", "/v1/edges/sets", bytes.NewReader([]byte(`{ "op" : "intersect", "count" : true, "sets" : [ `+tc.sets+` ] }`)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res = httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != tc.code {
+			t.Errorf("handler returned wrong status code for %s: got %v want %v",
+				tc.sets, status, tc.code)
+		}
+	}
+}
+
+func TestTraverseEndpoint(t *testing.T) {
+
+	testTableName := "test_traverse_edges"
+
+	store := testStore(t, testTableName)
+
+	// pages 4 -> 3 -> 2 -> 1
+	edgesList := []models.Edge{
+		{Name: &testTableName, SrcId: "4", DestId: "3", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "3", DestId: "2", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "2", DestId: "1", Status: models.ACTIVE},
+	}
+
+	_, _ = store.Save(&edgesList)
+
+	postBody := fmt.Sprintf(`
+    {
+      "start" : [4],
+      "hops" : [
+        { "name" : "%s", "direction" : "out", "repeat" : 10 }
+      ]
+    }
+  `, testTableName)
+
+	req := httptest.NewRequest("POST", "/v1/edges/traverse", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var response struct {
+		Nodes []models.ReachedNode `json:"nodes"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if len(response.Nodes) != 3 {
+		t.Fatalf("handler returned %d nodes, want %d", len(response.Nodes), 3)
+	}
+
+	root := response.Nodes[2]
+
+	if root.Id != "1" || root.Depth != 3 || fmt.Sprint(root.Path) != "[4 3 2 1]" {
+		t.Errorf("handler returned unexpected root: %v", root)
+	}
+}
+
+func TestTraverseEndpoint_EdgeTypes(t *testing.T) {
+
+	followName := "test_traverse_follows"
+	tagName := "test_traverse_tags"
+
+	store := testStore(t, followName, tagName)
+
+	// user 1 follows user 2, who follows tag 1
+	follows := []models.Edge{{Name: &followName, SrcId: "1", DestId: "2", Status: models.ACTIVE}}
+	tags := []models.Edge{{Name: &tagName, SrcId: "2", DestId: "1", Status: models.ACTIVE}}
+
+	_, _ = store.Save(&follows)
+	_, _ = store.Save(&tags)
+
+	postBody := fmt.Sprintf(`
+    {
+      "start" : [1],
+      "hops" : [
+        { "name" : "%s", "direction" : "out" },
+        { "name" : "%s", "direction" : "out" }
+      ]
+    }
+  `, followName, tagName)
+
+	req := httptest.NewRequest("POST", "/v1/edges/traverse", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var response struct {
+		Nodes []models.ReachedNode `json:"nodes"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	// tag 1 is not user 1, the start node
+	if len(response.Nodes) != 1 || response.Nodes[0].Id != "1" || fmt.Sprint(response.Nodes[0].Path) != "[1 2 1]" {
+		t.Errorf("handler returned unexpected nodes: %v", response.Nodes)
+	}
+}
+
+func TestTraverseEndpoint_UnknownEdge(t *testing.T) {
+
+	testTableName := "test_traverse_known_edges"
+
+	store := testStore(t, testTableName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	// hops after the first are checked before any edges are followed
+	for _, hops := range [][]string{
+		{"test_missing_edges", testTableName},
+		{testTableName, "test_missing_edges"},
+	} {
+
+		postBody := fmt.Sprintf(`
+      {
+        "start" : [1],
+        "hops" : [
+          { "name" : "%s", "direction" : "out" },
+          { "name" : "%s", "direction" : "out" }
+        ]
+      }
+    `, hops[0], hops[1])
+
+		req := httptest.NewRequest("POST", "/v1/edges/traverse", bytes.NewReader([]byte(postBody)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusNotFound {
+			t.Errorf("traversal of %v returned wrong status code: got %v want %v",
+				hops, status, http.StatusNotFound)
+		}
+	}
+}
+
+func TestTraverseEndpoint_UuidStart(t *testing.T) {
+
+	testTableName := "test_traverse_uuid_edges"
+
+	store := testStore(t)
+
+	if pgStore, ok := store.(*models.PostgresStore); ok {
+		// defer cleanup
+		defer func() {
+			database.DropTable(pgStore.Db, testTableName)
+			pgStore.Db.Exec("DELETE FROM loki_edge_types WHERE name = $1", testTableName)
+		}()
+	}
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	userId := "4f8b4d52-7ac1-4b8a-9a6e-3c8b1f0e2d11"
+	pageId := "0c5e1a3b-55d2-4e0f-8a43-6b7e9d2c4f00"
+
+	requests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "id_type" : "uuid" }`, testTableName), http.StatusOK},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "%s", "dest_id" : "%s" } ] }`, testTableName, userId, pageId), http.StatusOK},
+		{"/v1/edges/traverse", fmt.Sprintf(`{ "start" : [ "1" ], "hops" : [ { "name" : "%s", "direction" : "out" } ] }`, testTableName), http.StatusBadRequest},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, request.code)
+		}
+	}
+
+	body := fmt.Sprintf(`{ "start" : [ "%s" ], "hops" : [ { "name" : "%s", "direction" : "out" } ] }`, strings.ToUpper(userId), testTableName)
+
+	req := httptest.NewRequest("POST", "/v1/edges/traverse", bytes.NewReader([]byte(body)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var response struct {
+		Nodes []models.ReachedNode `json:"nodes"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if len(response.Nodes) != 1 || string(response.Nodes[0].Path[0]) != userId || string(response.Nodes[0].Id) != pageId {
+		t.Errorf("handler returned unexpected nodes: %v", response.Nodes)
+	}
+}
+
+func TestGetEdgeTypeEndpoint(t *testing.T) {
+
+	testTableName := "test_typed_edges"
+
+	store := testStore(t, testTableName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	postBody := fmt.Sprintf(`
+    {
+      "name": "%s",
+      "src_types": ["user"],
+      "dest_types": ["user", "page"]
+    }
+  `, testTableName)
+
+	req := httptest.NewRequest("POST", "/v1/edges/init", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	postBody = fmt.Sprintf(`
+    {
+      "edges" : [
+        { "name" : "%[1]s", "src_id" : 1, "src_type" : "user", "dest_id" : 2, "dest_type" : "page", "status" : "active" },
+        { "name" : "%[1]s", "src_id" : 2, "src_type" : "page", "dest_id" : 1, "dest_type" : "user", "status" : "active" }
+      ]
+    }
+  `, testTableName)
+
+	req = httptest.NewRequest("POST", "/v1/edges/save", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res = httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	req = httptest.NewRequest("GET", "/v1/edges/types/"+testTableName, nil)
+
+	res = httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	var response struct {
+		Type models.EdgeType `json:"type"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if len(response.Type.DestTypes) != 2 || response.Type.Stats == nil || response.Type.Stats.Rows != 0 {
+		t.Errorf("handler returned unexpected edge type: %v", response.Type)
+	}
+}
+
+func TestGetEdgeTypeEndpoint_CachedStats(t *testing.T) {
+
+	store, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer store.Close()
+
+	testTableName := "test_stats_edges"
+
+	_ = store.CreateType(&models.EdgeType{Name: testTableName})
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	statsRows := func() int64 {
+
+		req := httptest.NewRequest("GET", "/v1/edges/types/"+testTableName, nil)
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		var response struct {
+			Type models.EdgeType `json:"type"`
+		}
+
+		_ = json.NewDecoder(res.Body).Decode(&response)
+
+		if response.Type.Stats == nil {
+			t.Fatalf("handler returned an edge type without stats: %v", response.Type)
+		}
+
+		return response.Type.Stats.Rows
+	}
+
+	if rows := statsRows(); rows != 0 {
+		t.Errorf("Expected no rows, got %d", rows)
+	}
+
+	// writes through the store count the table again
+	_, _ = store.Save(&[]models.Edge{
+		{Name: &testTableName, SrcId: "1", DestId: "2", Status: models.ACTIVE},
+		{Name: &testTableName, SrcId: "1", DestId: "3", Status: models.ACTIVE},
+	})
+
+	if rows := statsRows(); rows != 2 {
+		t.Errorf("Expected 2 rows after the save, got %d", rows)
+	}
+
+	// writes behind the store are seen once the stats expire
+	_, _ = store.Db.Exec(fmt.Sprintf(`DELETE FROM "%s"`, testTableName))
+
+	if rows := statsRows(); rows != 2 {
+		t.Errorf("Expected the cached 2 rows, got %d", rows)
+	}
+}
+
+func TestSaveEdgesEndpoint_Schema(t *testing.T) {
+
+	testTableName := "test_schema_edges"
+
+	store := testStore(t, testTableName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	postBody := fmt.Sprintf(`
+    {
+      "name": "%s",
+      "schema": {
+        "type": "object",
+        "required": ["source"],
+        "properties": {
+          "source": { "type": "string" }
+        }
+      }
+    }
+  `, testTableName)
+
+	req := httptest.NewRequest("POST", "/v1/edges/init", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	postBody = fmt.Sprintf(`
+    {
+      "edges" : [
+        { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2, "data" : { "source" : "web" } },
+        { "name" : "%[1]s", "src_id" : 1, "dest_id" : 3, "data" : { "source" : 10 } },
+        { "name" : "%[1]s", "src_id" : 1, "dest_id" : 4, "data" : {} }
+      ]
+    }
+  `, testTableName)
+
+	req = httptest.NewRequest("POST", "/v1/edges/save", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res = httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusBadRequest)
+	}
+
+	var response AppError
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if response.Fields == nil || fmt.Sprint(*response.Fields) != "[edges.1.data.source edges.2.data.source]" {
+		t.Errorf("handler returned unexpected fields: %v", response.Fields)
+	}
+}
+
+func TestGetEdgeTypeEndpoint_NotFound(t *testing.T) {
+
+	store := testStore(t)
+
+	req := httptest.NewRequest("GET", "/v1/edges/types/test_missing_edges", nil)
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+}
+
+func TestMigrateEdgeTables(t *testing.T) {
+
+	testTableName := "test_unqualified_edges"
+	otherTableName := "test_unregistered_edges"
+
+	store := postgresStore(t, testTableName, otherTableName)
+
+	_ = models.RegisterEdgeType(store.Db, &models.EdgeType{Name: testTableName})
+
+	// tables created before edges had a qualifier, only one of which
+	// is the table of a registered edge type
+	for _, tableName := range []string{testTableName, otherTableName} {
+		if _, err := store.Db.Exec(fmt.Sprintf("ALTER TABLE %s DROP COLUMN qualifier", database.QuoteName(tableName))); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	migrated, err := database.MigrateEdgeTables(store.Db)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsName(migrated, testTableName) {
+		t.Errorf("Expected %s to be migrated, got %v", testTableName, migrated)
+	}
+
+	var hasQualifier bool
+
+	_ = store.Db.Get(&hasQualifier, `
+	  SELECT count(*) > 0 FROM information_schema.columns
+	  WHERE table_schema = current_schema() AND table_name = $1 AND column_name = 'qualifier'
+	`, otherTableName)
+
+	if containsName(migrated, otherTableName) || hasQualifier {
+		t.Errorf("Expected the look-alike table %s to be left alone, got %v", otherTableName, migrated)
+	}
+
+	if _, err := store.Save(&[]models.Edge{{Name: &testTableName, SrcId: "1", DestId: "2"}}); err != nil {
+		t.Errorf("Expected saves to the migrated table to succeed, got %v", err)
+	}
+}
+
+func TestMigrateEdgeTables_SQLite(t *testing.T) {
+
+	store, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer store.Close()
+
+	testTableName := "test_unqualified_edges"
+
+	_ = store.CreateType(&models.EdgeType{Name: testTableName})
+
+	// a table created before edges had a qualifier
+	if _, err := store.Db.Exec(fmt.Sprintf(`ALTER TABLE "%s" DROP COLUMN qualifier`, testTableName)); err != nil {
+		t.Fatal(err)
+	}
+
+	// & a table with the columns of edges that is not an edge type
+	otherTableName := "test_unregistered_edges"
+
+	if _, err := store.Db.Exec(fmt.Sprintf(`CREATE TABLE "%s" (id text, src_id text, src_type text, dest_id text, dest_type text, score real, data text, status text, updated text)`, otherTableName)); err != nil {
+		t.Fatal(err)
+	}
+
+	migrated, err := store.MigrateEdgeTables()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !containsName(migrated, testTableName) {
+		t.Errorf("Expected %s to be migrated, got %v", testTableName, migrated)
+	}
+
+	if containsName(migrated, otherTableName) {
+		t.Errorf("Expected the look-alike table %s to be left alone, got %v", otherTableName, migrated)
+	}
+
+	qualifier := "test"
+
+	if _, err := store.Save(&[]models.Edge{{Name: &testTableName, SrcId: "1", DestId: "2", Qualifier: &qualifier}}); err != nil {
+		t.Errorf("Expected saves to the migrated table to succeed, got %v", err)
+	}
+
+	// tables that have the column are left alone
+	if migrated, _ := store.MigrateEdgeTables(); len(migrated) != 0 {
+		t.Errorf("Expected no tables to be migrated again, got %v", migrated)
+	}
+}
+
+func containsName(names []string, name string) bool {
+
+	for _, item := range names {
+		if item == name {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestRunSQLEndpoint(t *testing.T) {
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	testTableName := "test_sql_edges"
+
+	_ = sqliteStore.CreateType(&models.EdgeType{Name: testTableName})
+	_, _ = sqliteStore.Save(&[]models.Edge{{Name: &testTableName, SrcId: "1", DestId: "2", Status: models.ACTIVE}})
+
+	os.Setenv("RAW_SQL_ENABLED", "true")
+
+	defer os.Unsetenv("RAW_SQL_ENABLED")
+
+	queries := map[models.EdgeStore]string{
+		sqliteStore: fmt.Sprintf(`SELECT *, '%[1]s' as name FROM \"%[1]s\"`, testTableName),
+	}
+
+	if os.Getenv(TEST_POSTGRES_ENV) != "" {
+		queries[postgresStore(t)] = "SELECT 1 as src_id, 2 as dest_id, '' as src_type, '' as dest_type, 0 as score, '1' as id, 'test_edge' as name, 'active' as status, now() as updated"
+	}
+
+	for store, query := range queries {
+
+		postBody := fmt.Sprintf(`{ "query" : "%s" }`, query)
+
+		req := httptest.NewRequest("POST", "/v1/edges/sql", bytes.NewReader([]byte(postBody)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+		handler := CreateRouter(store, &RateLimits{})
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusOK {
+			t.Errorf("%T returned wrong status code: got %v want %v",
+				store, status, http.StatusOK)
+		}
+
+		var response struct {
+			Edges []models.Edge `json:"edges"`
+		}
+
+		_ = json.NewDecoder(res.Body).Decode(&response)
+
+		if len(response.Edges) != 1 {
+			t.Errorf("%T returned unexpected edges: %v", store, response.Edges)
+		}
+	}
+}
+
+func TestRunSQLEndpoint_Disabled(t *testing.T) {
+
+	store := testStore(t)
+
+	postBody := `{"query" : "SELECT 1"}`
+
+	req := httptest.NewRequest("POST", "/v1/edges/sql", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+}
+
+func TestSaveEdgesEndpoint_LastWriteWins(t *testing.T) {
+
+	testTableName := "test_lww_edges"
+
+	store := testStore(t, testTableName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	earlier := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	later := time.Now().Format(time.RFC3339)
+
+	// the stale save, sent last, must not overwrite the edge
+	for _, update := range [][]string{{later, "active"}, {earlier, "deleted"}} {
+
+		postBody := fmt.Sprintf(`
+      {
+        "edges" : [
+          { "name" : "%s", "src_id" : 1, "dest_id" : 2, "status" : "%s", "updated" : "%s" }
+        ]
+      }
+    `, testTableName, update[1], update[0])
+
+		req := httptest.NewRequest("POST", "/v1/edges/save", bytes.NewReader([]byte(postBody)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v",
+				status, http.StatusOK)
+		}
+	}
+
+	edgesPtr, _, err := store.List(&models.EdgeQuery{Name: &testTableName})
+
+	if err != nil || len(*edgesPtr) != 1 {
+		t.Fatalf("stale save overwrote the edge: %v", err)
+	}
+
+	stale := time.Now().Add(-time.Minute)
+
+	deletes := []models.Edge{
+		{Name: &testTableName, SrcId: "1", DestId: "2", Updated: &stale},
+	}
+
+	_, _ = store.Delete(&deletes)
+
+	edgesPtr, _, _ = store.List(&models.EdgeQuery{Name: &testTableName})
+
+	if len(*edgesPtr) != 1 {
+		t.Errorf("stale delete overwrote the edge")
+	}
+}
+
+func TestDeleteEdgesEndpoint_OutOfOrder(t *testing.T) {
+
+	testTableName := "test_tombstone_edges"
+
+	store := testStore(t, testTableName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	earlier := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	later := time.Now().Format(time.RFC3339)
+
+	// the delete of an edge that was never saved arrives before its
+	// save, & a save arrives with the same time as a delete
+	requests := []struct {
+		path string
+		body string
+	}{
+		{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "score" : 5, "data" : { "key" : "value" }, "updated" : "%s" } ] }`, testTableName, later)},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "updated" : "%s" } ] }`, testTableName, earlier)},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "updated" : "%s" } ] }`, testTableName, later)},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusOK {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, http.StatusOK)
+		}
+	}
+
+	edge, err := store.Get(&models.Edge{Name: &testTableName, SrcId: "1", DestId: "2"})
+
+	if err != nil || edge == nil || edge.Status != models.DELETED {
+		t.Fatalf("saves brought back a deleted edge: %v %v", edge, err)
+	}
+
+	// deletes only write the ids & status of the edge
+	if edge.Score != 0 || edge.Data != nil {
+		t.Errorf("tombstone kept the score or data of the delete: %v %v", edge.Score, edge.Data)
+	}
+}
+
+func TestCombineSetsEndpoint_Unsupported(t *testing.T) {
+
+	postBody := `
+    {
+      "op" : "union",
+      "sets" : [
+        { "name" : "test_edge", "node_id" : 1, "direction" : "out" },
+        { "name" : "test_edge", "node_id" : 2, "direction" : "out" }
+      ]
+    }
+  `
+
+	req := httptest.NewRequest("POST", "/v1/edges/sets", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	store, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer store.Close()
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusNotImplemented {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotImplemented)
+	}
+}
+
+func TestEdgesEndpoints_SQLite(t *testing.T) {
+
+	store, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer store.Close()
+
+	testTableName := "test_sqlite_edges"
+	testNamespace := "test_app"
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	earlier := time.Now().Add(-time.Hour).Format(time.RFC3339)
+	later := time.Now().Format(time.RFC3339)
+
+	requests := []struct {
+		path string
+		body string
+	}{
+		{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "namespace" : "%s", "src_types" : ["user"] }`, testTableName, testNamespace)},
+		{"/v1/edges/save", fmt.Sprintf(`
+      {
+        "namespace" : "%[1]s",
+        "edges" : [
+          { "name" : "%[2]s", "src_id" : 1, "src_type" : "user", "dest_id" : 2, "score" : 1, "status" : "active", "updated" : "%[3]s", "data" : { "key" : "value" } },
+          { "name" : "%[2]s", "src_id" : 1, "src_type" : "user", "dest_id" : 3, "score" : 2, "status" : "active", "updated" : "%[3]s" },
+          { "name" : "%[2]s", "src_id" : 1, "src_type" : "user", "dest_id" : 4, "score" : 3, "status" : "active", "updated" : "%[3]s" }
+        ]
+      }
+    `, testNamespace, testTableName, later)},
+		// stale saves change nothing, deletes of missing edges leave tombstones
+		{"/v1/edges/save", fmt.Sprintf(`
+      {
+        "namespace" : "%[1]s",
+        "edges" : [
+          { "name" : "%[2]s", "src_id" : 1, "src_type" : "user", "dest_id" : 2, "status" : "deleted", "updated" : "%[3]s" }
+        ]
+      }
+    `, testNamespace, testTableName, earlier)},
+		{"/v1/edges/delete", fmt.Sprintf(`
+      {
+        "namespace" : "%[1]s",
+        "edges" : [
+          { "name" : "%[2]s", "src_id" : 1, "dest_id" : 4 },
+          { "name" : "%[2]s", "src_id" : 1, "dest_id" : 5 }
+        ]
+      }
+    `, testNamespace, testTableName)},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusOK {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, http.StatusOK)
+		}
+	}
+
+	qualifiedName := testNamespace + "." + testTableName
+
+	destIds := make([]models.NodeId, 0)
+	cursor := ""
+
+	for page := 0; page < 3; page++ {
+
+		edgesPtr, nextCursor, err := store.List(&models.EdgeQuery{
+			Name:    &qualifiedName,
+			SrcIds:  []models.NodeId{"1"},
+			OrderBy: "-score",
+			Limit:   1,
+			Cursor:  cursor,
+		})
+
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		for _, edge := range *edgesPtr {
+			destIds = append(destIds, edge.DestId)
+		}
+
+		if nextCursor == "" {
+			break
+		}
+
+		cursor = nextCursor
+	}
+
+	if fmt.Sprint(destIds) != "[3 2]" {
+		t.Errorf("query returned unexpected edges: %v", destIds)
+	}
+
+	edge, err := store.Get(&models.Edge{Name: &qualifiedName, SrcId: "1", DestId: "2"})
+
+	if err != nil || edge == nil || edge.Data == nil || (*edge.Data)["key"] != "value" {
+		t.Errorf("could not get the saved edge: %v %v", edge, err)
+	}
+
+	counts, err := store.Count(&models.CountQuery{
+		Name:   &qualifiedName,
+		SrcIds: []models.NodeId{"1", "2"},
+		Status: []string{models.ACTIVE, models.DELETED},
+	})
+
+	if err != nil || counts["1"] != 4 || counts["2"] != 0 {
+		t.Errorf("count returned unexpected counts: %v %v", counts, err)
+	}
+
+	req := httptest.NewRequest("GET", "/v1/edges/types/"+qualifiedName, nil)
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	var response struct {
+		Type models.EdgeType `json:"type"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if response.Type.Stats == nil || response.Type.Stats.Statuses[models.DELETED] != 2 || len(response.Type.SrcTypes) != 1 {
+		t.Errorf("handler returned unexpected edge type: %v", response.Type)
+	}
+}
+
+func TestSaveEdgesEndpoint_Qualified(t *testing.T) {
+
+	testTableName := "test_qualified_edges"
+
+	store := testStore(t, testTableName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	// user 1 liked post 2 twice, & the second like was taken back
+	requests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "qualified" : true }`, testTableName), http.StatusOK},
+		{"/v1/edges/save", fmt.Sprintf(`
+      {
+        "edges" : [
+          { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2, "qualifier" : "2018-01-01" },
+          { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2, "qualifier" : "2018-02-01" }
+        ]
+      }
+    `, testTableName), http.StatusOK},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2 } ] }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "qualifier" : "2018:03" } ] }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2 } ] }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "qualifier" : "2018-02-01" } ] }`, testTableName), http.StatusOK},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, request.code)
+		}
+	}
+
+	edgesPtr, _, err := store.List(&models.EdgeQuery{Name: &testTableName, SrcIds: []models.NodeId{"1"}})
+
+	if err != nil || len(*edgesPtr) != 1 || *(*edgesPtr)[0].Qualifier != "2018-01-01" {
+		t.Fatalf("query returned unexpected edges: %v %v", edgesPtr, err)
+	}
+
+	edgesPtr, _, _ = store.List(&models.EdgeQuery{
+		Name:       &testTableName,
+		Qualifiers: []string{"2018-02-01"},
+		Status:     []string{models.DELETED},
+	})
+
+	if len(*edgesPtr) != 1 || (*edgesPtr)[0].Id != "1:2:2018-02-01" {
+		t.Errorf("query returned unexpected deleted edges: %v", *edgesPtr)
+	}
+}
+
+func TestSaveEdgesEndpoint_StringIds(t *testing.T) {
+
+	testTableName := "test_uuid_edges"
+
+	store := testStore(t)
+
+	if pgStore, ok := store.(*models.PostgresStore); ok {
+		// defer cleanup
+		defer func() {
+			database.DropTable(pgStore.Db, testTableName)
+			pgStore.Db.Exec("DELETE FROM loki_edge_types WHERE name = $1", testTableName)
+		}()
+	}
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	userId := "4f8b4d52-7ac1-4b8a-9a6e-3c8b1f0e2d11"
+
+	requests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "id_type" : "uuid" }`, testTableName), http.StatusOK},
+		{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "id_type" : "int" }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/init", `{ "name" : "test_other_edges", "id_type" : "float" }`, http.StatusBadRequest},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "%s", "dest_id" : 2 } ] }`, testTableName, userId), http.StatusBadRequest},
+		{"/v1/edges/save", fmt.Sprintf(`
+      {
+        "edges" : [
+          { "name" : "%[1]s", "src_id" : "%[2]s", "dest_id" : "0c5e1a3b-55d2-4e0f-8a43-6b7e9d2c4f00" }
+        ]
+      }
+    `, testTableName, userId), http.StatusOK},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, request.code)
+		}
+	}
+
+	body := fmt.Sprintf(`{ "name" : "%s", "src_id" : [ "%s" ] }`, testTableName, userId)
+
+	req := httptest.NewRequest("POST", "/v1/edges/query", bytes.NewReader([]byte(body)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	var response struct {
+		Edges []map[string]interface{} `json:"edges"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if len(response.Edges) != 1 || response.Edges[0]["src_id"] != userId {
+		t.Errorf("handler returned unexpected edges: %v", response.Edges)
+	}
+}
+
+func TestSaveEdgesEndpoint_UuidCase(t *testing.T) {
+
+	testTableName := "test_uuid_case_edges"
+
+	store := testStore(t)
+
+	if pgStore, ok := store.(*models.PostgresStore); ok {
+		// defer cleanup
+		defer func() {
+			database.DropTable(pgStore.Db, testTableName)
+			pgStore.Db.Exec("DELETE FROM loki_edge_types WHERE name = $1", testTableName)
+		}()
+	}
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	userId := "4F8B4D52-7AC1-4B8A-9A6E-3C8B1F0E2D11"
+	pageId := "0c5e1a3b-55d2-4e0f-8a43-6b7e9d2c4f00"
+
+	// the ids are stored, & compared, in lowercase
+	requests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "id_type" : "uuid" }`, testTableName), http.StatusOK},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "%s", "dest_id" : "%s" } ] }`, testTableName, userId, pageId), http.StatusOK},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "%s", "dest_id" : "%s", "if" : { "exists" : true } } ] }`, testTableName, strings.ToLower(userId), pageId), http.StatusOK},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "%s", "dest_id" : "%s", "if" : { "exists" : false } } ] }`, testTableName, userId, pageId), http.StatusConflict},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, request.code)
+		}
+	}
+
+	body := fmt.Sprintf(`{ "name" : "%s", "src_id" : [ "%s" ] }`, testTableName, userId)
+
+	req := httptest.NewRequest("POST", "/v1/edges/count", bytes.NewReader([]byte(body)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	var response struct {
+		Counts map[string]int64 `json:"counts"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&response)
+
+	if len(response.Counts) != 1 || response.Counts[strings.ToLower(userId)] != 1 {
+		t.Errorf("handler returned unexpected counts: %v", response.Counts)
+	}
+}
+
+func TestSaveEdgesEndpoint_SeparatorInIds(t *testing.T) {
+
+	testTableName := "test_string_edges"
+
+	store := testStore(t)
+
+	if pgStore, ok := store.(*models.PostgresStore); ok {
+		// defer cleanup
+		defer func() {
+			database.DropTable(pgStore.Db, testTableName)
+			pgStore.Db.Exec("DELETE FROM loki_edge_types WHERE name = $1", testTableName)
+		}()
+	}
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	// `a:b` -> `c` & `a` -> `b:c` would both be `a:b:c`
+	requests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "id_type" : "string" }`, testTableName), http.StatusOK},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "a:b", "dest_id" : "c" } ] }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "a", "dest_id" : "b:c" } ] }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "a:b", "dest_id" : "c" } ] }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "a", "dest_id" : "b" } ] }`, testTableName), http.StatusOK},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, request.code)
+		}
+	}
+}
+
+func TestSaveEdgesEndpoint_UnregisteredIds(t *testing.T) {
+
+	testTableName := "test_legacy_edges"
+
+	store := postgresStore(t, testTableName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	// the table was created before edge types were registered, with
+	// integer node ids
+	requests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : "abc", "dest_id" : 2 } ] }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : "abc" } ] }`, testTableName), http.StatusBadRequest},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2 } ] }`, testTableName), http.StatusOK},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, request.code)
+		}
+	}
+}
+
+func TestSaveEdgesEndpoint_Inverse(t *testing.T) {
+
+	followName := "test_follows"
+	followedByName := "test_followed_by"
+	friendName := "test_friends"
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	stores := []models.EdgeStore{testStore(t), sqliteStore}
+
+	if pgStore, ok := stores[0].(*models.PostgresStore); ok {
+		// defer cleanup
+		defer func() {
+			for _, edgeName := range []string{followName, followedByName, friendName} {
+				database.DropTable(pgStore.Db, edgeName)
+				pgStore.Db.Exec("DELETE FROM loki_edge_types WHERE name = $1", edgeName)
+			}
+		}()
+	}
+
+	for _, store := range stores {
+
+		handler := CreateRouter(store, &RateLimits{})
+
+		requests := []struct {
+			path string
+			body string
+			code int
+		}{
+			{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "inverse" : "%s", "src_types" : ["user"] }`, followName, followedByName), http.StatusOK},
+			{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "symmetric" : true }`, friendName), http.StatusOK},
+			{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "inverse" : "%s" }`, friendName, followedByName), http.StatusBadRequest},
+			{"/v1/edges/save", fmt.Sprintf(`
+        {
+          "edges" : [
+            { "name" : "%[1]s", "src_id" : 1, "src_type" : "user", "dest_id" : 2, "dest_type" : "page" },
+            { "name" : "%[2]s", "src_id" : 1, "dest_id" : 3 },
+            { "name" : "%[2]s", "src_id" : 1, "dest_id" : 4 }
+          ]
+        }
+      `, followName, friendName), http.StatusOK},
+			{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 4, "dest_id" : 1 } ] }`, friendName), http.StatusOK},
+		}
+
+		for _, request := range requests {
+
+			req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+			req.Header.Add("Content-Type", "application/json")
+
+			res := httptest.NewRecorder()
+
+			handler.ServeHTTP(res, req)
+
+			if status := res.Code; status != request.code {
+				t.Fatalf("%s returned wrong status code: got %v want %v",
+					request.path, status, request.code)
+			}
+		}
+
+		edge, err := store.Get(&models.Edge{Name: &followedByName, SrcId: "2", DestId: "1"})
+
+		if err != nil || edge == nil || edge.Status != models.ACTIVE || *edge.SrcType != "page" {
+			t.Errorf("inverse edge was not saved: %v %v", edge, err)
+		}
+
+		edgesPtr, _, err := store.List(&models.EdgeQuery{Name: &friendName, DestIds: []models.NodeId{"1"}})
+
+		if err != nil || len(*edgesPtr) != 1 || (*edgesPtr)[0].SrcId != "3" {
+			t.Errorf("symmetric edges were not mirrored: %v %v", edgesPtr, err)
+		}
+
+		edge, _ = store.Get(&models.Edge{Name: &friendName, SrcId: "1", DestId: "4"})
+
+		if edge == nil || edge.Status != models.DELETED {
+			t.Errorf("symmetric edge was not deleted: %v", edge)
+		}
+	}
+}
+
+func TestSaveEdgesEndpoint_InvalidMirror(t *testing.T) {
+
+	likeName := "test_likes"
+	likedByName := "test_liked_by"
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	stores := []models.EdgeStore{testStore(t), sqliteStore}
+
+	if pgStore, ok := stores[0].(*models.PostgresStore); ok {
+		// defer cleanup
+		defer func() {
+			for _, edgeName := range []string{likeName, likedByName} {
+				database.DropTable(pgStore.Db, edgeName)
+				pgStore.Db.Exec("DELETE FROM loki_edge_types WHERE name = $1", edgeName)
+			}
+		}()
+	}
+
+	for _, store := range stores {
+
+		handler := CreateRouter(store, &RateLimits{})
+
+		// the inverse keeps its definition, which only has pages as
+		// sources, so likes of anything else have invalid mirrors
+		requests := []struct {
+			path string
+			body string
+			code int
+		}{
+			{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "src_types" : ["page"] }`, likedByName), http.StatusOK},
+			{"/v1/edges/init", fmt.Sprintf(`{ "name" : "%s", "inverse" : "%s" }`, likeName, likedByName), http.StatusOK},
+			{"/v1/edges/save", fmt.Sprintf(`
+        {
+          "edges" : [
+            { "name" : "%[1]s", "src_id" : 1, "src_type" : "user", "dest_id" : 2, "dest_type" : "page" },
+            { "name" : "%[1]s", "src_id" : 1, "src_type" : "user", "dest_id" : 3, "dest_type" : "post" }
+          ]
+        }
+      `, likeName), http.StatusBadRequest},
+		}
+
+		var res *httptest.ResponseRecorder
+
+		for _, request := range requests {
+
+			req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+			req.Header.Add("Content-Type", "application/json")
+
+			res = httptest.NewRecorder()
+
+			handler.ServeHTTP(res, req)
+
+			if status := res.Code; status != request.code {
+				t.Fatalf("%s returned wrong status code: got %v want %v",
+					request.path, status, request.code)
+			}
+		}
+
+		var appErr AppError
+
+		_ = json.NewDecoder(res.Body).Decode(&appErr)
+
+		if appErr.Fields == nil || fmt.Sprint(*appErr.Fields) != "[edges.1.src_type]" {
+			t.Errorf("handler returned unexpected fields: %v", appErr)
+		}
+
+		if edge, _ := store.Get(&models.Edge{Name: &likeName, SrcId: "1", DestId: "2"}); edge != nil {
+			t.Errorf("edges of a save with an invalid mirror were saved: %v", edge)
+		}
+	}
+}
+
+func TestBatchEdgesEndpoint(t *testing.T) {
+
+	followName := "test_batch_follows"
+	likeName := "test_batch_likes"
+
+	store := testStore(t, followName, likeName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	earlier := time.Now().Add(-time.Hour).Format(time.RFC3339)
+
+	requests := []struct {
+		body string
+		code int
+	}{
+		{fmt.Sprintf(`
+      {
+        "operations" : [
+          { "action" : "save", "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2, "updated" : "%[3]s" } ] },
+          { "action" : "save", "edges" : [ { "name" : "%[2]s", "src_id" : 1, "dest_id" : 100 }, { "name" : "%[1]s", "src_id" : 1, "dest_id" : 3 } ] },
+          { "action" : "delete", "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2 } ] }
+        ]
+      }
+    `, followName, likeName, earlier), http.StatusOK},
+		// nothing is written when an operation is invalid
+		{fmt.Sprintf(`
+      {
+        "operations" : [
+          { "action" : "save", "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 4 } ] },
+          { "action" : "update", "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 5 } ] }
+        ]
+      }
+    `, followName), http.StatusBadRequest},
+		// or when an edge type does not exist
+		{fmt.Sprintf(`
+      {
+        "operations" : [
+          { "action" : "save", "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 4 } ] },
+          { "action" : "delete", "edges" : [ { "name" : "test_batch_missing", "src_id" : 1, "dest_id" : 5 } ] }
+        ]
+      }
+    `, followName), http.StatusNotFound},
+		{`{ "operations" : [] }`, http.StatusBadRequest},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", "/v1/edges/batch", bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Fatalf("handler returned wrong status code: got %v want %v",
+				status, request.code)
+		}
+	}
+
+	counts, err := store.Count(&models.CountQuery{
+		Name:   &followName,
+		SrcIds: []models.NodeId{"1"},
+		Status: []string{models.ACTIVE, models.DELETED},
+	})
+
+	if err != nil || counts["1"] != 2 {
+		t.Errorf("batch wrote unexpected follows: %v %v", counts, err)
+	}
+
+	edge, _ := store.Get(&models.Edge{Name: &followName, SrcId: "1", DestId: "2"})
+
+	if edge == nil || edge.Status != models.DELETED {
+		t.Errorf("batch did not delete the edge: %v", edge)
+	}
+
+	edge, _ = store.Get(&models.Edge{Name: &likeName, SrcId: "1", DestId: "100"})
+
+	if edge == nil || edge.Status != models.ACTIVE {
+		t.Errorf("batch did not save the edge: %v", edge)
+	}
+}
+
+func TestSaveEdgesEndpoint_Preconditions(t *testing.T) {
+
+	testTableName := "test_invite_edges"
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	_ = sqliteStore.CreateType(&models.EdgeType{Name: testTableName})
+
+	updated := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	for _, store := range []models.EdgeStore{testStore(t, testTableName), sqliteStore} {
+
+		handler := CreateRouter(store, &RateLimits{})
+
+		requests := []struct {
+			path   string
+			body   string
+			code   int
+			fields string
+		}{
+			{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "score" : 1, "updated" : "%s", "if" : { "exists" : false } } ] }`, testTableName, updated), http.StatusOK, ""},
+			{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "score" : 2, "if" : { "exists" : false } } ] }`, testTableName), http.StatusConflict, "[edges.0]"},
+			{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "if" : { "updated" : "2018-01-01T00:00:00Z" } } ] }`, testTableName), http.StatusConflict, "[edges.0]"},
+			{"/v1/edges/batch", fmt.Sprintf(`
+        {
+          "operations" : [
+            { "action" : "save", "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 3, "if" : { "exists" : false } } ] },
+            { "action" : "delete", "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2, "if" : { "status" : "deleted" } } ] }
+          ]
+        }
+      `, testTableName), http.StatusConflict, "[operations.1.edges.0]"},
+			{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "score" : 3, "if" : { "status" : "active", "updated" : "%s" } } ] }`, testTableName, updated), http.StatusOK, ""},
+		}
+
+		for _, request := range requests {
+
+			req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+			req.Header.Add("Content-Type", "application/json")
+
+			res := httptest.NewRecorder()
+
+			handler.ServeHTTP(res, req)
+
+			if status := res.Code; status != request.code {
+				t.Fatalf("%s returned wrong status code: got %v want %v",
+					request.path, status, request.code)
+			}
+
+			var response AppError
+
+			_ = json.NewDecoder(res.Body).Decode(&response)
+
+			if request.fields != "" && (response.Fields == nil || fmt.Sprint(*response.Fields) != request.fields) {
+				t.Errorf("%s returned unexpected fields: %v", request.path, response.Fields)
+			}
+		}
+
+		edge, _ := store.Get(&models.Edge{Name: &testTableName, SrcId: "1", DestId: "2"})
+
+		if edge == nil || edge.Status != models.ACTIVE || edge.Score != 3 {
+			t.Errorf("conditional writes left unexpected edge: %v", edge)
+		}
+
+		edge, _ = store.Get(&models.Edge{Name: &testTableName, SrcId: "1", DestId: "3"})
+
+		if edge != nil {
+			t.Errorf("batch with failed preconditions saved an edge: %v", edge)
+		}
+	}
+}
+
+func TestSaveEdgesEndpoint_Results(t *testing.T) {
+
+	testTableName := "test_result_edges"
+
+	sqliteStore, err := models.NewSQLiteStore(":memory:")
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	defer sqliteStore.Close()
+
+	_ = sqliteStore.CreateType(&models.EdgeType{Name: testTableName})
+
+	older := time.Now().Add(-2 * time.Hour).UTC().Format(time.RFC3339)
+	updated := time.Now().Add(-time.Hour).UTC().Format(time.RFC3339)
+
+	for _, store := range []models.EdgeStore{testStore(t, testTableName), sqliteStore} {
+
+		handler := CreateRouter(store, &RateLimits{})
+
+		requests := []struct {
+			path     string
+			body     string
+			outcomes string
+		}{
+			{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2, "updated" : "%[2]s" }, { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2, "updated" : "%[3]s" }, { "name" : "%[1]s", "src_id" : 1, "dest_id" : 3, "updated" : "%[2]s" } ] }`, testTableName, updated, older), "[created stale created]"},
+			{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2 }, { "name" : "%[1]s", "src_id" : 1, "dest_id" : 3, "updated" : "%[2]s" } ] }`, testTableName, older), "[updated stale]"},
+			{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2 }, { "name" : "%[1]s", "src_id" : 1, "dest_id" : 4 } ] }`, testTableName), "[deleted not_found]"},
+			{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2 } ] }`, testTableName), "[not_found]"},
+			{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%[1]s", "src_id" : 1, "dest_id" : 2 } ] }`, testTableName), "[created]"},
+		}
+
+		for _, request := range requests {
+
+			req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+			req.Header.Add("Content-Type", "application/json")
+
+			res := httptest.NewRecorder()
+
+			handler.ServeHTTP(res, req)
+
+			if status := res.Code; status != http.StatusOK {
+				t.Fatalf("%s returned wrong status code: got %v want %v",
+					request.path, status, http.StatusOK)
+			}
+
+			var response struct {
+				Results []models.EdgeResult `json:"results"`
+			}
+
+			_ = json.NewDecoder(res.Body).Decode(&response)
+
+			outcomes := make([]string, len(response.Results))
+
+			for idx, result := range response.Results {
+				outcomes[idx] = result.Outcome
+			}
+
+			if fmt.Sprint(outcomes) != request.outcomes {
+				t.Errorf("%s returned unexpected outcomes: got %v want %v", request.path, outcomes, request.outcomes)
+			}
+		}
+	}
 }
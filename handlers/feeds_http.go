@@ -0,0 +1,132 @@
+package handlers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi"
+	"github.com/sonnes/loki/models"
+)
+
+func InitFeedEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	var jsonBody models.Feed
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		WriteDecodeError(w, err)
+		return
+	}
+
+	defer r.Body.Close()
+
+	if err := jsonBody.Validate(); err != nil {
+		WriteValidationError(w, err)
+		return
+	}
+
+	if !AuthorizedAdmin(w, r) {
+		return
+	}
+
+	feedStore, ok := store.(models.FeedStore)
+
+	if !ok {
+		WriteUnsupported(w, "Feeds")
+		return
+	}
+
+	err := feedStore.CreateFeed(&jsonBody)
+
+	if err != nil {
+		WriteModelError(w, err)
+		return
+	}
+
+	responseJson := make(map[string]string)
+
+	responseJson["success"] = "true"
+	responseJson["message"] = fmt.Sprintf("%s - feed has been created successfully", jsonBody.Name)
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
+
+func ReadFeedEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	params := r.URL.Query()
+
+	userId, err := strconv.ParseInt(params.Get("user_id"), 10, 64)
+
+	if err != nil || userId == 0 {
+		WriteError(w, &AppError{
+			Code:    http.StatusBadRequest,
+			Message: "You must provide the `user_id` to read the feed of",
+			Fields:  &[]string{"user_id"},
+		})
+		return
+	}
+
+	limit := 0
+
+	if params.Get("limit") != "" {
+		limit, err = strconv.Atoi(params.Get("limit"))
+
+		if err != nil {
+			WriteError(w, &AppError{
+				Code:    http.StatusBadRequest,
+				Message: err.Error(),
+				Fields:  &[]string{"limit"},
+			})
+			return
+		}
+	}
+
+	feedStore, ok := store.(models.FeedStore)
+
+	if !ok {
+		WriteUnsupported(w, "Feeds")
+		return
+	}
+
+	feed, err := feedStore.GetFeed(chi.URLParam(r, "name"))
+
+	if err != nil {
+		WriteError(w, &AppError{
+			Code:    http.StatusInternalServerError,
+			Message: err.Error(),
+		})
+		return
+	}
+
+	if feed == nil {
+		WriteError(w, &AppError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("%s - feed does not exist", chi.URLParam(r, "name")),
+		})
+		return
+	}
+
+	if !Authorized(w, r, models.READ, feed.ContentEdge, feed.FollowEdge) {
+		return
+	}
+
+	itemsPtr, cursor, err := feedStore.ReadFeed(feed, userId, limit, params.Get("cursor"))
+
+	if err != nil {
+		WriteModelError(w, err)
+		return
+	}
+
+	responseJson := make(map[string]interface{})
+
+	responseJson["success"] = "true"
+	responseJson["items"] = *itemsPtr
+	responseJson["cursor"] = nil
+
+	if cursor != "" {
+		responseJson["cursor"] = cursor
+	}
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
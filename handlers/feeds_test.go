@@ -0,0 +1,290 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sonnes/loki/database"
+	"github.com/sonnes/loki/models"
+)
+
+func TestReadFeedEndpoint(t *testing.T) {
+
+	followTable := "test_feed_follow"
+	postTable := "test_feed_post"
+	feedName := "test_home"
+
+	store := postgresStore(t, followTable, postTable)
+
+	// defer cleanup
+	defer func() {
+		store.Db.Exec("DELETE FROM loki_feeds WHERE name = $1", feedName)
+		database.DropTable(store.Db, database.FeedInboxTableName(feedName))
+		database.DropTable(store.Db, database.FeedPullTableName(feedName))
+	}()
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	postBody := fmt.Sprintf(`
+    {
+      "name" : "%s",
+      "content_edge" : "%s",
+      "follow_edge" : "%s",
+      "fanout_limit" : 1
+    }
+  `, feedName, postTable, followTable)
+
+	req := httptest.NewRequest("POST", "/v1/feeds/init", bytes.NewReader([]byte(postBody)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v",
+			status, http.StatusOK)
+	}
+
+	// user 1 follows 10 & 20, author 20 has too many followers to fan out
+	follows := []models.Edge{
+		{Name: &followTable, SrcId: "1", DestId: "10", Status: models.ACTIVE},
+		{Name: &followTable, SrcId: "1", DestId: "20", Status: models.ACTIVE},
+		{Name: &followTable, SrcId: "2", DestId: "20", Status: models.ACTIVE},
+	}
+
+	_, _ = store.Save(&follows)
+
+	posts := []models.Edge{
+		{Name: &postTable, SrcId: "10", DestId: "100", Score: 1, Status: models.ACTIVE},
+		{Name: &postTable, SrcId: "20", DestId: "200", Score: 2, Status: models.ACTIVE},
+		{Name: &postTable, SrcId: "10", DestId: "101", Score: 3, Status: models.ACTIVE},
+	}
+
+	_, _ = store.Save(&posts)
+
+	itemIds := make([]models.NodeId, 0)
+	cursor := ""
+
+	for page := 0; page < 3; page++ {
+
+		req = httptest.NewRequest("GET", fmt.Sprintf("/v1/feeds/%s?user_id=1&limit=2&cursor=%s", feedName, cursor), nil)
+
+		res = httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v",
+				status, http.StatusOK)
+		}
+
+		var response struct {
+			Items  []models.Edge `json:"items"`
+			Cursor *string       `json:"cursor"`
+		}
+
+		_ = json.NewDecoder(res.Body).Decode(&response)
+
+		for _, item := range response.Items {
+			itemIds = append(itemIds, item.DestId)
+		}
+
+		if response.Cursor == nil {
+			break
+		}
+
+		cursor = *response.Cursor
+	}
+
+	if fmt.Sprint(itemIds) != "[101 200 100]" {
+		t.Errorf("handler returned unexpected items: %v", itemIds)
+	}
+}
+
+// stubFeedStore is a MemoryStore with feeds, whose creation fails
+// with err.
+type stubFeedStore struct {
+	*models.MemoryStore
+	err error
+}
+
+func (store *stubFeedStore) CreateFeed(feed *models.Feed) error { return store.err }
+
+func (store *stubFeedStore) GetFeed(feedName string) (*models.Feed, error) { return nil, nil }
+
+func (store *stubFeedStore) ReadFeed(feed *models.Feed, userId int64, limit int, cursor string) (*[]models.Edge, string, error) {
+	return &[]models.Edge{}, "", nil
+}
+
+func TestInitFeedEndpoint_InvalidEdges(t *testing.T) {
+
+	postBody := `
+    {
+      "name" : "test_home",
+      "content_edge" : "test_feed_post",
+      "follow_edge" : "test_feed_follow"
+    }
+  `
+
+	for _, tc := range []struct {
+		err  error
+		code int
+	}{
+		{&models.UnknownEdgeError{Name: "test_feed_post"}, http.StatusNotFound},
+		{&models.ValidationError{Field: "content_edge", Message: "Feeds need edges with int node ids"}, http.StatusBadRequest},
+	} {
+
+		store := &stubFeedStore{
+			MemoryStore: models.NewMemoryStore(),
+			err:         tc.err,
+		}
+
+		req := httptest.NewRequest("POST", "/v1/feeds/init", bytes.NewReader([]byte(postBody)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+		handler := CreateRouter(store, &RateLimits{})
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != tc.code {
+			t.Errorf("handler returned wrong status code for %v: got %v want %v",
+				tc.err, status, tc.code)
+		}
+	}
+}
+
+func TestReadFeedEndpoint_NotFound(t *testing.T) {
+
+	store := postgresStore(t)
+
+	req := httptest.NewRequest("GET", "/v1/feeds/test_missing_feed?user_id=1", nil)
+
+	res := httptest.NewRecorder()
+	handler := CreateRouter(store, &RateLimits{})
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v",
+			status, http.StatusNotFound)
+	}
+}
+
+func TestReadFeedEndpoint_Unfollow(t *testing.T) {
+
+	followTable := "test_unfollow_follow"
+	postTable := "test_unfollow_post"
+	feedName := "test_unfollow_home"
+
+	store := postgresStore(t, followTable, postTable)
+
+	defer func() {
+		store.Db.Exec("DELETE FROM loki_feeds WHERE name = $1", feedName)
+		database.DropTable(store.Db, database.FeedInboxTableName(feedName))
+		database.DropTable(store.Db, database.FeedPullTableName(feedName))
+	}()
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	if err := models.CreateFeed(store.Db, &models.Feed{
+		Name:        feedName,
+		ContentEdge: postTable,
+		FollowEdge:  followTable,
+		FanoutLimit: 1,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	readFeed := func(userId string) string {
+
+		req := httptest.NewRequest("GET", fmt.Sprintf("/v1/feeds/%s?user_id=%s", feedName, userId), nil)
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		var response struct {
+			Items []models.Edge `json:"items"`
+		}
+
+		_ = json.NewDecoder(res.Body).Decode(&response)
+
+		itemIds := make([]models.NodeId, 0)
+
+		for _, item := range response.Items {
+			itemIds = append(itemIds, item.DestId)
+		}
+
+		return fmt.Sprint(itemIds)
+	}
+
+	// user 1 follows 10 & 20, author 20 has too many followers to fan out
+	follows := []models.Edge{
+		{Name: &followTable, SrcId: "1", DestId: "10", Status: models.ACTIVE},
+		{Name: &followTable, SrcId: "1", DestId: "20", Status: models.ACTIVE},
+		{Name: &followTable, SrcId: "2", DestId: "20", Status: models.ACTIVE},
+	}
+
+	_, _ = store.Save(&follows)
+
+	posts := []models.Edge{
+		{Name: &postTable, SrcId: "10", DestId: "100", Score: 1, Status: models.ACTIVE},
+		{Name: &postTable, SrcId: "20", DestId: "200", Score: 2, Status: models.ACTIVE},
+	}
+
+	_, _ = store.Save(&posts)
+
+	// items pushed from 10 are left out once user 1 unfollows 10
+	_, _ = store.Delete(&[]models.Edge{{Name: &followTable, SrcId: "1", DestId: "10"}})
+
+	if items := readFeed("1"); items != "[200]" {
+		t.Errorf("Expected only the items of followed authors, got %s", items)
+	}
+
+	// author 20 is back under the limit, and is pushed from then on
+	_, _ = store.Delete(&[]models.Edge{{Name: &followTable, SrcId: "2", DestId: "20"}})
+	_, _ = store.Save(&[]models.Edge{{Name: &postTable, SrcId: "20", DestId: "201", Score: 3, Status: models.ACTIVE}})
+
+	var pulled int
+
+	_ = store.Db.Get(&pulled, fmt.Sprintf("SELECT count(*) FROM %s", database.QuoteName(database.FeedPullTableName(feedName))))
+
+	if pulled != 0 {
+		t.Errorf("Expected author 20 to be removed from the pulled authors, got %d pulled", pulled)
+	}
+
+	var inboxed int
+
+	_ = store.Db.Get(&inboxed, fmt.Sprintf("SELECT count(*) FROM %s WHERE user_id = 1", database.QuoteName(database.FeedInboxTableName(feedName))))
+
+	if inboxed != 2 {
+		t.Errorf("Expected both items of author 20 in the inbox of user 1, got %d", inboxed)
+	}
+
+	if items := readFeed("1"); items != "[201 200]" {
+		t.Errorf("Expected the items of author 20, got %s", items)
+	}
+
+	// following 10 again backfills its older items
+	_, _ = store.Save(&[]models.Edge{{Name: &followTable, SrcId: "1", DestId: "10", Status: models.ACTIVE}})
+
+	if items := readFeed("1"); items != "[201 200 100]" {
+		t.Errorf("Expected the older items of author 10 to be backfilled, got %s", items)
+	}
+
+	// feeds of edges without tables are rejected
+	err := models.CreateFeed(store.Db, &models.Feed{
+		Name:        "test_missing_home",
+		ContentEdge: "test_missing_post",
+		FollowEdge:  followTable,
+	})
+
+	if _, ok := err.(*models.UnknownEdgeError); !ok {
+		t.Errorf("Expected an unknown edge error for a missing content edge, got %v", err)
+	}
+}
@@ -0,0 +1,292 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/sonnes/loki/models"
+)
+
+type contextKey string
+
+const (
+	API_KEY_HEADER string = "X-Api-Key"
+
+	apiKeyContext contextKey = "api_key"
+)
+
+// Authenticate answers with a 401 for requests without the token of
+// a key that is not revoked, in the `X-Api-Key` header or as a
+// bearer token, which are limited by the IP of the client. Requests
+// with a key are authorized by the endpoints, see Authorize.
+func Authenticate(store models.EdgeStore, limits *RateLimits) func(http.Handler) http.Handler {
+
+	return func(next http.Handler) http.Handler {
+
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+			key, err := TokenKey(store, requestToken(r))
+
+			if err != nil {
+				WriteModelError(w, limits.Unauthenticated(r.Context(), r.RemoteAddr, readRequest(r), err))
+				return
+			}
+
+			next.ServeHTTP(w, r.WithContext(WithKey(r.Context(), key)))
+		})
+	}
+}
+
+// TokenKey is the key of the token, or a 401 for missing tokens &
+// tokens of keys that do not exist or are revoked.
+func TokenKey(store models.EdgeStore, token string) (*models.ApiKey, error) {
+
+	keyStore, ok := store.(models.KeyStore)
+
+	if !ok {
+		return nil, &AppError{
+			Code:    http.StatusNotImplemented,
+			Message: "API keys are not supported by this edge store",
+		}
+	}
+
+	if token == "" {
+		return nil, &AppError{
+			Code:    http.StatusUnauthorized,
+			Message: "Requests must have an API key, in the `X-Api-Key` header",
+		}
+	}
+
+	key, err := keyStore.GetKey(models.TokenId(token))
+
+	if err != nil {
+		return nil, err
+	}
+
+	if key == nil || !key.Matches(token) {
+		return nil, &AppError{
+			Code:    http.StatusUnauthorized,
+			Message: "API key is invalid or has been revoked",
+		}
+	}
+
+	return key, nil
+}
+
+func requestToken(r *http.Request) string {
+
+	if token := r.Header.Get(API_KEY_HEADER); token != "" {
+		return token
+	}
+
+	authorization := r.Header.Get("Authorization")
+
+	if strings.HasPrefix(authorization, "Bearer ") {
+		return strings.TrimPrefix(authorization, "Bearer ")
+	}
+
+	return ""
+}
+
+// WithKey is the context of a request authenticated with the key.
+func WithKey(ctx context.Context, key *models.ApiKey) context.Context {
+	return context.WithValue(ctx, apiKeyContext, key)
+}
+
+// ContextKey is the key a request was authenticated with, or nil
+// when API keys are not enabled.
+func ContextKey(ctx context.Context) *models.ApiKey {
+
+	key, _ := ctx.Value(apiKeyContext).(*models.ApiKey)
+
+	return key
+}
+
+func RequestKey(r *http.Request) *models.ApiKey {
+	return ContextKey(r.Context())
+}
+
+// Authorize returns a 403 when the key of the context is not allowed
+// the action on every edge type.
+func Authorize(ctx context.Context, action string, edgeNames ...string) error {
+
+	key := ContextKey(ctx)
+
+	if key == nil {
+		return nil
+	}
+
+	for _, edgeName := range edgeNames {
+
+		if !key.Allows(action, edgeName) {
+			return &AppError{
+				Code:    http.StatusForbidden,
+				Message: fmt.Sprintf("API key is not allowed to %s %s", action, edgeName),
+			}
+		}
+	}
+
+	return nil
+}
+
+// Authorized answers with a 403 and returns false when the key of
+// the request is not allowed the action on every edge type.
+func Authorized(w http.ResponseWriter, r *http.Request, action string, edgeNames ...string) bool {
+
+	if err := Authorize(r.Context(), action, edgeNames...); err != nil {
+		WriteModelError(w, err)
+		return false
+	}
+
+	return true
+}
+
+// AuthorizeAdmin returns a 403 when the key of the context does not
+// administer every edge type.
+func AuthorizeAdmin(ctx context.Context) error {
+
+	if key := ContextKey(ctx); key != nil && !key.IsAdmin() {
+		return &AppError{
+			Code:    http.StatusForbidden,
+			Message: "API key is not allowed to administer loki",
+		}
+	}
+
+	return nil
+}
+
+func AuthorizedAdmin(w http.ResponseWriter, r *http.Request) bool {
+
+	if err := AuthorizeAdmin(r.Context()); err != nil {
+		WriteModelError(w, err)
+		return false
+	}
+
+	return true
+}
+
+// edgeNames lists the names of the edges, for authorizing writes.
+func edgeNames(edgesPtr *[]models.Edge) []string {
+
+	names := make([]string, 0)
+
+	for edgeName := range models.GroupByEdgeName(edgesPtr) {
+		names = append(names, edgeName)
+	}
+
+	return names
+}
+
+func CreateKeyEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	var jsonBody models.ApiKey
+
+	if err := json.NewDecoder(r.Body).Decode(&jsonBody); err != nil {
+		WriteDecodeError(w, err)
+		return
+	}
+
+	defer r.Body.Close()
+
+	if !AuthorizedAdmin(w, r) {
+		return
+	}
+
+	if err := jsonBody.Validate(); err != nil {
+		WriteValidationError(w, err)
+		return
+	}
+
+	keyStore, ok := store.(models.KeyStore)
+
+	if !ok {
+		WriteUnsupported(w, "API keys")
+		return
+	}
+
+	token, err := keyStore.CreateKey(&jsonBody)
+
+	if err != nil {
+		WriteModelError(w, err)
+		return
+	}
+
+	responseJson := make(map[string]interface{})
+
+	responseJson["success"] = "true"
+	responseJson["key"] = jsonBody
+	responseJson["token"] = token
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
+
+func ListKeysEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	if !AuthorizedAdmin(w, r) {
+		return
+	}
+
+	keyStore, ok := store.(models.KeyStore)
+
+	if !ok {
+		WriteUnsupported(w, "API keys")
+		return
+	}
+
+	keysPtr, err := keyStore.ListKeys()
+
+	if err != nil {
+		WriteModelError(w, err)
+		return
+	}
+
+	responseJson := make(map[string]interface{})
+
+	responseJson["success"] = "true"
+	responseJson["keys"] = *keysPtr
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
+
+// RevokeKeyEndpoint revokes a key, requests with its token are
+// refused from then on.
+func RevokeKeyEndpoint(store models.EdgeStore, w http.ResponseWriter, r *http.Request) {
+
+	if !AuthorizedAdmin(w, r) {
+		return
+	}
+
+	keyStore, ok := store.(models.KeyStore)
+
+	if !ok {
+		WriteUnsupported(w, "API keys")
+		return
+	}
+
+	id := chi.URLParam(r, "id")
+
+	revoked, err := keyStore.RevokeKey(id)
+
+	if err != nil {
+		WriteModelError(w, err)
+		return
+	}
+
+	if !revoked {
+		WriteError(w, &AppError{
+			Code:    http.StatusNotFound,
+			Message: fmt.Sprintf("%s - key does not exist or is already revoked", id),
+		})
+		return
+	}
+
+	responseJson := make(map[string]string)
+
+	responseJson["success"] = "true"
+
+	WriteJson(w, responseJson, http.StatusOK)
+}
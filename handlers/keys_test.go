@@ -0,0 +1,144 @@
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/sonnes/loki/models"
+)
+
+func TestKeysEndpoints(t *testing.T) {
+
+	testNamespace := "test_keys"
+	followName := testNamespace + ".follows"
+	otherName := "test_key_edges"
+
+	store := models.NewMemoryStore()
+
+	_ = store.CreateType(&models.EdgeType{Namespace: testNamespace, Name: "follows"})
+	_ = store.CreateType(&models.EdgeType{Name: otherName})
+
+	adminToken, err := store.CreateKey(&models.ApiKey{
+		Name:   "admin",
+		Scopes: models.Scopes{{Edges: []string{models.ALL_EDGES}, Actions: models.KEY_ACTIONS}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("API_KEYS_ENABLED", "true")
+
+	defer os.Unsetenv("API_KEYS_ENABLED")
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	send := func(method string, path string, token string, body string) *httptest.ResponseRecorder {
+
+		req := httptest.NewRequest(method, path, bytes.NewReader([]byte(body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		if token != "" {
+			req.Header.Add(API_KEY_HEADER, token)
+		}
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		return res
+	}
+
+	res := send("POST", "/v1/keys", adminToken, fmt.Sprintf(`
+    {
+      "name" : "writer",
+      "scopes" : [ { "edges" : [ "%s.*" ], "actions" : [ "read", "write" ] } ]
+    }
+  `, testNamespace))
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("could not mint a key: %v %s", res.Code, res.Body.String())
+	}
+
+	var minted struct {
+		Key   models.ApiKey `json:"key"`
+		Token string        `json:"token"`
+	}
+
+	_ = json.NewDecoder(res.Body).Decode(&minted)
+
+	saveFollow := fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2 } ] }`, followName)
+
+	requests := []struct {
+		method string
+		path   string
+		token  string
+		body   string
+		code   int
+	}{
+		{"POST", "/v1/edges/save", "", saveFollow, http.StatusUnauthorized},
+		{"POST", "/v1/edges/save", minted.Key.Id + ".invalid", saveFollow, http.StatusUnauthorized},
+		{"POST", "/v1/edges/save", minted.Token, saveFollow, http.StatusOK},
+		{"POST", "/v1/edges/query", minted.Token, fmt.Sprintf(`{ "name" : "%s", "src_id" : [ 1 ] }`, followName), http.StatusOK},
+		{"POST", "/v1/edges/delete", minted.Token, saveFollow, http.StatusForbidden},
+		{"POST", "/v1/edges/save", minted.Token, fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2 } ] }`, otherName), http.StatusForbidden},
+		{"POST", "/v1/edges/init", minted.Token, fmt.Sprintf(`{ "name" : "likes", "namespace" : "%s" }`, testNamespace), http.StatusForbidden},
+		{"GET", "/v1/keys", minted.Token, "", http.StatusForbidden},
+		{"GET", "/v1/keys", adminToken, "", http.StatusOK},
+		{"DELETE", "/v1/keys/" + minted.Key.Id, adminToken, "", http.StatusOK},
+		{"DELETE", "/v1/keys/" + minted.Key.Id, adminToken, "", http.StatusNotFound},
+		{"POST", "/v1/edges/save", minted.Token, saveFollow, http.StatusUnauthorized},
+	}
+
+	for _, request := range requests {
+
+		res := send(request.method, request.path, request.token, request.body)
+
+		if status := res.Code; status != request.code {
+			t.Errorf("%s %s returned wrong status code: got %v want %v",
+				request.method, request.path, status, request.code)
+		}
+	}
+}
+
+func TestKeysEndpoints_KeysDisabled(t *testing.T) {
+
+	os.Unsetenv("API_KEYS_ENABLED")
+
+	store := models.NewMemoryStore()
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{"POST", "/v1/keys", `{ "name" : "admin", "scopes" : [ { "edges" : [ "*" ], "actions" : [ "admin" ] } ] }`},
+		{"GET", "/v1/keys", ""},
+		{"DELETE", "/v1/keys/1", ""},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest(request.method, request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if res.Code != http.StatusNotFound {
+			t.Errorf("%s %s was served with keys disabled: %v %s", request.method, request.path, res.Code, res.Body.String())
+		}
+	}
+
+	if keys, err := store.ListKeys(); err != nil || len(*keys) != 0 {
+		t.Errorf("a key was minted with keys disabled: %v %v", keys, err)
+	}
+}
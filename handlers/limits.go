@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strconv"
+)
+
+const (
+	MAX_BODY_BYTES_ENV string = "MAX_BODY_BYTES"
+	MAX_EDGES_ENV      string = "MAX_EDGES_PER_REQUEST"
+
+	DEFAULT_MAX_BODY_BYTES int64 = 10 << 20
+	DEFAULT_MAX_EDGES      int   = 10000
+
+	requestLimitsContext contextKey = "request_limits"
+)
+
+// RequestLimits are the largest body, in bytes, & the most edges a
+// request can have.
+type RequestLimits struct {
+	BodyBytes int64
+	Edges     int
+}
+
+// RequestLimitsFromEnv reads the limits, which default to
+// DEFAULT_MAX_BODY_BYTES & DEFAULT_MAX_EDGES.
+func RequestLimitsFromEnv() (*RequestLimits, error) {
+
+	limits := &RequestLimits{BodyBytes: DEFAULT_MAX_BODY_BYTES, Edges: DEFAULT_MAX_EDGES}
+
+	if value := os.Getenv(MAX_BODY_BYTES_ENV); value != "" {
+
+		bodyBytes, err := strconv.ParseInt(value, 10, 64)
+
+		if err != nil || bodyBytes <= 0 {
+			return nil, fmt.Errorf("%s must be a positive number of bytes, got %s", MAX_BODY_BYTES_ENV, value)
+		}
+
+		limits.BodyBytes = bodyBytes
+	}
+
+	if value := os.Getenv(MAX_EDGES_ENV); value != "" {
+
+		edges, err := strconv.Atoi(value)
+
+		if err != nil || edges <= 0 {
+			return nil, fmt.Errorf("%s must be a positive number of edges, got %s", MAX_EDGES_ENV, value)
+		}
+
+		limits.Edges = edges
+	}
+
+	return limits, nil
+}
+
+// Limit stops reading request bodies past the largest body, see
+// WriteDecodeError, and passes the limits on to WithinEdgeLimit.
+func (limits *RequestLimits) Limit(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		r.Body = http.MaxBytesReader(w, r.Body, limits.BodyBytes)
+
+		next.ServeHTTP(w, r.WithContext(WithRequestLimits(r.Context(), limits)))
+	})
+}
+
+// WithRequestLimits is the context of a request with the limits.
+func WithRequestLimits(ctx context.Context, limits *RequestLimits) context.Context {
+	return context.WithValue(ctx, requestLimitsContext, limits)
+}
+
+// WithinEdgeLimit returns a 413 when the request has more edges
+// than allowed.
+func WithinEdgeLimit(ctx context.Context, count int, field string) error {
+
+	limits, _ := ctx.Value(requestLimitsContext).(*RequestLimits)
+
+	if limits == nil || count <= limits.Edges {
+		return nil
+	}
+
+	return &AppError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: fmt.Sprintf("Request has %d edges, requests can have at most %d", count, limits.Edges),
+		Fields:  &[]string{field},
+	}
+}
+
+// WriteDecodeError answers with a 413 for bodies larger than allowed
+// and with a 400 for every other body that could not be read.
+func WriteDecodeError(w http.ResponseWriter, err error) {
+
+	var tooLarge *http.MaxBytesError
+
+	if errors.As(err, &tooLarge) {
+		WriteError(w, &AppError{
+			Code:    http.StatusRequestEntityTooLarge,
+			Message: fmt.Sprintf("Request body is larger than %d bytes", tooLarge.Limit),
+		})
+		return
+	}
+
+	WriteError(w, &AppError{
+		Code:    http.StatusBadRequest,
+		Message: err.Error(),
+	})
+}
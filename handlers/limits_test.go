@@ -0,0 +1,100 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/sonnes/loki/models"
+)
+
+func TestSaveEdgesEndpoint_RequestLimits(t *testing.T) {
+
+	testTableName := "test_request_limits"
+
+	store := testStore(t, testTableName)
+
+	os.Setenv(MAX_BODY_BYTES_ENV, "512")
+	os.Setenv(MAX_EDGES_ENV, "2")
+
+	defer os.Unsetenv(MAX_BODY_BYTES_ENV)
+	defer os.Unsetenv(MAX_EDGES_ENV)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	edge := fmt.Sprintf(`{ "name" : "%s", "src_id" : 1, "dest_id" : 2 }`, testTableName)
+
+	requests := []struct {
+		path string
+		body string
+		code int
+	}{
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ %s, %s ] }`, edge, edge), http.StatusOK},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ %s, %s, %s ] }`, edge, edge, edge), http.StatusRequestEntityTooLarge},
+		{"/v1/edges/delete", fmt.Sprintf(`{ "edges" : [ %s, %s, %s ] }`, edge, edge, edge), http.StatusRequestEntityTooLarge},
+		{"/v1/edges/batch", fmt.Sprintf(`
+      {
+        "operations" : [
+          { "action" : "save", "edges" : [ %[1]s, %[1]s ] },
+          { "action" : "delete", "edges" : [ %[1]s ] }
+        ]
+      }
+    `, edge), http.StatusRequestEntityTooLarge},
+		{"/v1/edges/save", fmt.Sprintf(`{ "edges" : [ { "name" : "%s", "src_id" : 1, "dest_id" : 2, "data" : { "bio" : "%s" } } ] }`, testTableName, strings.Repeat("a", 512)), http.StatusRequestEntityTooLarge},
+	}
+
+	for _, request := range requests {
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(request.body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Errorf("%s returned wrong status code: got %v want %v",
+				request.path, status, request.code)
+		}
+	}
+}
+
+// Saves of more edges than fit in one statement are written in chunks.
+func TestSaveEdgesEndpoint_LargeBatch(t *testing.T) {
+
+	testTableName := "test_large_batch"
+
+	store := testStore(t, testTableName)
+
+	handler := CreateRouter(store, &RateLimits{})
+
+	edges := make([]string, 8000)
+
+	for idx := range edges {
+		edges[idx] = fmt.Sprintf(`{ "name" : "%s", "src_id" : 1, "dest_id" : %d }`, testTableName, idx+2)
+	}
+
+	body := fmt.Sprintf(`{ "edges" : [ %s ] }`, strings.Join(edges, ", "))
+
+	req := httptest.NewRequest("POST", "/v1/edges/save", bytes.NewReader([]byte(body)))
+	req.Header.Add("Content-Type", "application/json")
+
+	res := httptest.NewRecorder()
+
+	handler.ServeHTTP(res, req)
+
+	if status := res.Code; status != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s",
+			status, http.StatusOK, res.Body.String())
+	}
+
+	counts, err := store.Count(&models.CountQuery{Name: &testTableName, SrcIds: []models.NodeId{"1"}})
+
+	if err != nil || counts["1"] != int64(len(edges)) {
+		t.Errorf("large batch saved unexpected edges: %v %v", counts, err)
+	}
+}
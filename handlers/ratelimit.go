@@ -0,0 +1,303 @@
+package handlers
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// Budgets per minute, of every client, budgets that are not set
+	// are not limited.
+	RATE_LIMIT_READS_ENV  string = "RATE_LIMIT_READS"
+	RATE_LIMIT_WRITES_ENV string = "RATE_LIMIT_WRITES"
+	RATE_LIMIT_EDGES_ENV  string = "RATE_LIMIT_EDGES"
+
+	// Clients kept in a limiter, buckets that are full again are
+	// dropped first, then the buckets of the clients seen longest ago.
+	MAX_BUCKETS int = 10000
+
+	rateLimitsContext contextKey = "rate_limits"
+)
+
+// POST endpoints that only read edges, every other POST or DELETE
+// is a write.
+var READ_PATHS = map[string]bool{
+	"/v1/edges/query":    true,
+	"/v1/edges/count":    true,
+	"/v1/edges/sets":     true,
+	"/v1/edges/traverse": true,
+	"/v1/edges/sql":      true,
+}
+
+type tokenBucket struct {
+	tokens  float64
+	updated time.Time
+}
+
+// RateLimiter keeps a token bucket of every client, refilled with
+// the budget of a minute over the minute, up to the budget.
+type RateLimiter struct {
+	lock       sync.Mutex
+	perMinute  float64
+	buckets    map[string]*tokenBucket
+	maxBuckets int
+	now        func() time.Time
+}
+
+func NewRateLimiter(perMinute float64) *RateLimiter {
+	return &RateLimiter{
+		perMinute:  perMinute,
+		buckets:    make(map[string]*tokenBucket),
+		maxBuckets: MAX_BUCKETS,
+		now:        time.Now,
+	}
+}
+
+// Take takes the tokens from the bucket of the client, or returns
+// how long the client has to wait for them. Takes of more tokens
+// than the budget go through when the bucket is full, and leave the
+// bucket in debt.
+func (limiter *RateLimiter) Take(client string, tokens float64) time.Duration {
+
+	limiter.lock.Lock()
+	defer limiter.lock.Unlock()
+
+	now := limiter.now()
+
+	bucket, ok := limiter.buckets[client]
+
+	if !ok {
+		limiter.prune(now)
+
+		bucket = &tokenBucket{tokens: limiter.perMinute, updated: now}
+		limiter.buckets[client] = bucket
+	}
+
+	bucket.tokens = limiter.refill(bucket, now)
+	bucket.updated = now
+
+	needed := math.Min(tokens, limiter.perMinute)
+
+	if bucket.tokens >= needed {
+		bucket.tokens -= tokens
+		return 0
+	}
+
+	wait := (needed - bucket.tokens) / limiter.perMinute * float64(time.Minute)
+
+	return time.Duration(wait)
+}
+
+func (limiter *RateLimiter) refill(bucket *tokenBucket, now time.Time) float64 {
+
+	refilled := bucket.tokens + now.Sub(bucket.updated).Minutes()*limiter.perMinute
+
+	return math.Min(refilled, limiter.perMinute)
+}
+
+// prune makes room for a new bucket. Clients that are dropped start
+// again with a full bucket, so only the clients seen longest ago are
+// dropped once no bucket is full.
+func (limiter *RateLimiter) prune(now time.Time) {
+
+	if len(limiter.buckets) < limiter.maxBuckets {
+		return
+	}
+
+	for client, bucket := range limiter.buckets {
+		if limiter.refill(bucket, now) >= limiter.perMinute {
+			delete(limiter.buckets, client)
+		}
+	}
+
+	if len(limiter.buckets) < limiter.maxBuckets {
+		return
+	}
+
+	clients := make([]string, 0, len(limiter.buckets))
+
+	for client := range limiter.buckets {
+		clients = append(clients, client)
+	}
+
+	sort.Slice(clients, func(i, j int) bool {
+		return limiter.buckets[clients[i]].updated.Before(limiter.buckets[clients[j]].updated)
+	})
+
+	// drop a tenth at once, so clients over the cap do not sort the
+	// buckets on every request
+	for _, client := range clients[:len(clients)-limiter.maxBuckets*9/10] {
+		delete(limiter.buckets, client)
+	}
+}
+
+// RateLimits are the budgets of requests that read, requests that
+// write & of the edges written, which are nil when not limited.
+type RateLimits struct {
+	Reads  *RateLimiter
+	Writes *RateLimiter
+	Edges  *RateLimiter
+}
+
+// RateLimitsFromEnv reads the budgets, see RATE_LIMIT_READS_ENV.
+func RateLimitsFromEnv() (*RateLimits, error) {
+
+	limits := &RateLimits{}
+
+	for env, limiter := range map[string]**RateLimiter{
+		RATE_LIMIT_READS_ENV:  &limits.Reads,
+		RATE_LIMIT_WRITES_ENV: &limits.Writes,
+		RATE_LIMIT_EDGES_ENV:  &limits.Edges,
+	} {
+
+		value := os.Getenv(env)
+
+		if value == "" {
+			continue
+		}
+
+		perMinute, err := strconv.ParseFloat(value, 64)
+
+		if err != nil || perMinute <= 0 {
+			return nil, fmt.Errorf("%s must be a positive number per minute, got %s", env, value)
+		}
+
+		*limiter = NewRateLimiter(perMinute)
+	}
+
+	return limits, nil
+}
+
+// RateLimitedError is returned to clients out of a budget, which
+// can retry after the wait.
+type RateLimitedError struct {
+	Wait    time.Duration
+	Message string
+}
+
+func (err *RateLimitedError) Error() string {
+	return err.Message
+}
+
+type rateClient struct {
+	limits *RateLimits
+	client string
+}
+
+// Admit takes a request of the client from the budget of reads or
+// writes, and returns the context AllowEdges limits the client
+// with.
+func (limits *RateLimits) Admit(ctx context.Context, client string, read bool) (context.Context, error) {
+
+	limiter := limits.Writes
+
+	if read {
+		limiter = limits.Reads
+	}
+
+	if limiter != nil {
+
+		if wait := limiter.Take(client, 1); wait > 0 {
+			return ctx, &RateLimitedError{Wait: wait, Message: "Too many requests"}
+		}
+	}
+
+	return context.WithValue(ctx, rateLimitsContext, &rateClient{limits: limits, client: client}), nil
+}
+
+// Unauthenticated takes a request that failed authentication from
+// the budget of the IP of the address, so clients without a valid
+// key are limited too. It returns the RateLimitedError once the IP
+// is out of the budget, or the error of the authentication.
+func (limits *RateLimits) Unauthenticated(ctx context.Context, address string, read bool, err error) error {
+
+	if _, limitErr := limits.Admit(ctx, ClientKey(ctx, address), read); limitErr != nil {
+		return limitErr
+	}
+
+	return err
+}
+
+// Limit answers with a 429 when the client is out of the budget of
+// reads or writes, see Admit.
+func (limits *RateLimits) Limit(next http.Handler) http.Handler {
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+
+		ctx, err := limits.Admit(r.Context(), clientKey(r), readRequest(r))
+
+		if err != nil {
+			WriteModelError(w, err)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// AllowEdges returns a RateLimitedError when the client is out of
+// the budget of edges to write.
+func AllowEdges(ctx context.Context, count int) error {
+
+	rate, _ := ctx.Value(rateLimitsContext).(*rateClient)
+
+	if rate == nil || rate.limits.Edges == nil {
+		return nil
+	}
+
+	if wait := rate.limits.Edges.Take(rate.client, float64(count)); wait > 0 {
+		return &RateLimitedError{Wait: wait, Message: fmt.Sprintf("Too many edges, the request has %d", count)}
+	}
+
+	return nil
+}
+
+// readRequest tells if the request only reads edges.
+func readRequest(r *http.Request) bool {
+	return r.Method == http.MethodGet || READ_PATHS[strings.TrimSuffix(r.URL.Path, "/")]
+}
+
+// clientKey is the key the request was authenticated with, or the IP
+// of the client, set by middleware.RealIP from X-Forwarded-For or
+// X-Real-IP. Clients can set those headers, so the IP can only be
+// trusted behind a proxy that overwrites them.
+func clientKey(r *http.Request) string {
+	return ClientKey(r.Context(), r.RemoteAddr)
+}
+
+// ClientKey is the key of the context, or the IP of the address.
+func ClientKey(ctx context.Context, address string) string {
+
+	if key := ContextKey(ctx); key != nil {
+		return "key:" + key.Id
+	}
+
+	host, _, err := net.SplitHostPort(address)
+
+	if err != nil {
+		host = address
+	}
+
+	return "ip:" + host
+}
+
+// WriteRateLimited answers with a 429, telling the client when to
+// retry in whole seconds.
+func WriteRateLimited(w http.ResponseWriter, wait time.Duration, message string) {
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+
+	WriteError(w, &AppError{
+		Code:    http.StatusTooManyRequests,
+		Message: message,
+	})
+}
@@ -0,0 +1,177 @@
+package handlers
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestRateLimiter(t *testing.T) {
+
+	now := time.Now()
+
+	limiter := NewRateLimiter(60)
+	limiter.now = func() time.Time { return now }
+
+	if wait := limiter.Take("a", 60); wait != 0 {
+		t.Fatalf("full bucket did not allow its budget, wait %v", wait)
+	}
+
+	if wait := limiter.Take("a", 1); wait != time.Second {
+		t.Errorf("empty bucket returned unexpected wait: %v", wait)
+	}
+
+	if wait := limiter.Take("b", 1); wait != 0 {
+		t.Errorf("clients share a bucket, wait %v", wait)
+	}
+
+	now = now.Add(2 * time.Second)
+
+	if wait := limiter.Take("a", 2); wait != 0 {
+		t.Errorf("bucket was not refilled, wait %v", wait)
+	}
+
+	// takes of more than the budget need a full bucket
+	now = now.Add(time.Minute)
+
+	if wait := limiter.Take("a", 120); wait != 0 {
+		t.Errorf("full bucket did not allow a large take, wait %v", wait)
+	}
+
+	if wait := limiter.Take("a", 1); wait != time.Minute+time.Second {
+		t.Errorf("bucket in debt returned unexpected wait: %v", wait)
+	}
+}
+
+func TestRateLimiter_Prune(t *testing.T) {
+
+	now := time.Now()
+
+	limiter := NewRateLimiter(60)
+	limiter.maxBuckets = 10
+	limiter.now = func() time.Time { return now }
+
+	for idx := 0; idx < 100; idx++ {
+		limiter.Take(fmt.Sprintf("client-%d", idx), 1)
+		now = now.Add(time.Millisecond)
+	}
+
+	if count := len(limiter.buckets); count > limiter.maxBuckets {
+		t.Fatalf("limiter kept %d buckets, more than %d", count, limiter.maxBuckets)
+	}
+
+	if _, ok := limiter.buckets["client-99"]; !ok {
+		t.Errorf("limiter dropped the bucket of the latest client")
+	}
+
+	if _, ok := limiter.buckets["client-0"]; ok {
+		t.Errorf("limiter kept the bucket of the oldest client")
+	}
+}
+
+func TestSaveEdgesEndpoint_RateLimited(t *testing.T) {
+
+	testTableName := "test_limited_edges"
+
+	store := testStore(t, testTableName)
+
+	os.Setenv(RATE_LIMIT_WRITES_ENV, "3")
+	os.Setenv(RATE_LIMIT_EDGES_ENV, "4")
+
+	defer os.Unsetenv(RATE_LIMIT_WRITES_ENV)
+	defer os.Unsetenv(RATE_LIMIT_EDGES_ENV)
+
+	limits, err := RateLimitsFromEnv()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := CreateRouter(store, limits)
+
+	requests := []struct {
+		path  string
+		edges int
+		code  int
+	}{
+		{"/v1/edges/save", 3, http.StatusOK},
+		{"/v1/edges/save", 2, http.StatusTooManyRequests},
+		{"/v1/edges/delete", 1, http.StatusOK},
+		// the query is not a write
+		{"/v1/edges/query", 0, http.StatusOK},
+		{"/v1/edges/save", 1, http.StatusTooManyRequests},
+	}
+
+	for _, request := range requests {
+
+		body := fmt.Sprintf(`{ "name" : "%s", "src_id" : [ 1 ] }`, testTableName)
+
+		if request.edges > 0 {
+			body = `{ "edges" : [ `
+
+			for idx := 0; idx < request.edges; idx++ {
+				if idx > 0 {
+					body += ", "
+				}
+
+				body += fmt.Sprintf(`{ "name" : "%s", "src_id" : 1, "dest_id" : %d }`, testTableName, idx+2)
+			}
+
+			body += ` ] }`
+		}
+
+		req := httptest.NewRequest("POST", request.path, bytes.NewReader([]byte(body)))
+		req.Header.Add("Content-Type", "application/json")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != request.code {
+			t.Fatalf("%s returned wrong status code: got %v want %v",
+				request.path, status, request.code)
+		}
+
+		if request.code == http.StatusTooManyRequests && res.Header().Get("Retry-After") == "" {
+			t.Errorf("%s did not tell when to retry", request.path)
+		}
+	}
+}
+
+func TestListEdgeTypesEndpoint_RateLimitedUnauthenticated(t *testing.T) {
+
+	store := testStore(t)
+
+	os.Setenv("API_KEYS_ENABLED", "true")
+	os.Setenv(RATE_LIMIT_READS_ENV, "2")
+
+	defer os.Unsetenv("API_KEYS_ENABLED")
+	defer os.Unsetenv(RATE_LIMIT_READS_ENV)
+
+	limits, err := RateLimitsFromEnv()
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	handler := CreateRouter(store, limits)
+
+	// requests with invalid keys are limited by the IP of the client
+	for _, code := range []int{http.StatusUnauthorized, http.StatusUnauthorized, http.StatusTooManyRequests} {
+
+		req := httptest.NewRequest("GET", "/v1/edges/types", nil)
+		req.Header.Add("X-Api-Key", "invalid")
+
+		res := httptest.NewRecorder()
+
+		handler.ServeHTTP(res, req)
+
+		if status := res.Code; status != code {
+			t.Fatalf("handler returned wrong status code: got %v want %v", status, code)
+		}
+	}
+}
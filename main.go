@@ -1,30 +1,107 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
+	"net"
 	"net/http"
 	"os"
+	"strings"
 
 	"github.com/sonnes/loki/database"
 	"github.com/sonnes/loki/handlers"
+	"github.com/sonnes/loki/models"
+	"github.com/sonnes/loki/rpc"
+)
+
+const (
+	MEMORY_STORE  string = "memory"
+	SQLITE_PREFIX string = "sqlite://"
 )
 
 func main() {
 
+	rebuildCounts := flag.String("rebuild-counts", "", "recompute the counters of an edge type and exit")
+	mintAdminKey := flag.String("mint-admin-key", "", "mint an API key, with the given name, that administers every edge type and exit")
+
+	flag.Parse()
+
 	databaseURL := os.Getenv("POSTGRES_CONNECTION")
 
-	// Database connection
-	db := database.InitDB(databaseURL)
+	store, err := openStore(databaseURL)
+
+	if err != nil {
+		log.Fatalf("could not open the edge store: %v\n", err)
+	}
 
-	defer db.Close()
+	defer store.Close()
 
-	if err := db.Ping(); err != nil {
-		log.Fatalf("could not ping to database: %v\n", err)
+	if *rebuildCounts != "" {
+		counterStore, ok := store.(models.CounterStore)
+
+		if !ok {
+			log.Fatalf("counters are only kept in Postgres\n")
+		}
+
+		if err := counterStore.RebuildCounts(*rebuildCounts); err != nil {
+			log.Fatalf("could not rebuild counters: %v\n", err)
+		}
+
+		log.Printf("Rebuilt counters of %s", *rebuildCounts)
+		return
+	}
+
+	if *mintAdminKey != "" {
+		keyStore, ok := store.(models.KeyStore)
+
+		if !ok {
+			log.Fatalf("API keys are not kept in this store\n")
+		}
+
+		key := &models.ApiKey{
+			Name:   *mintAdminKey,
+			Scopes: models.Scopes{{Edges: []string{models.ALL_EDGES}, Actions: models.KEY_ACTIONS}},
+		}
+
+		token, err := keyStore.CreateKey(key)
+
+		if err != nil {
+			log.Fatalf("could not mint the key: %v\n", err)
+		}
+
+		fmt.Println(token)
+		return
+	}
+
+	if _, ok := store.(models.KeyStore); os.Getenv("API_KEYS_ENABLED") == "true" && !ok {
+		log.Fatalf("API keys are not kept in this store\n")
 	}
 
-	router := handlers.CreateRouter(db)
+	// shared by the HTTP & gRPC APIs, so clients have one budget
+	rateLimits, err := handlers.RateLimitsFromEnv()
 
-	go handlers.StartPubsubListen(db)
+	if err != nil {
+		log.Fatalf("could not read the rate limits: %v\n", err)
+	}
+
+	router := handlers.CreateRouter(store, rateLimits)
+
+	go handlers.StartPubsubListen(store)
+
+	// gRPC Server
+	grpcPort := env("GRPC_PORT", "9090")
+
+	listener, err := net.Listen("tcp", ":"+grpcPort)
+
+	if err != nil {
+		log.Fatalf("could not listen on the gRPC port: %v\n", err)
+	}
+
+	go func() {
+		log.Printf("Serving gRPC on port %s", grpcPort)
+		log.Fatal(rpc.CreateServer(store, rateLimits).Serve(listener))
+	}()
 
 	// API Server
 	port := env("PORT", "8080")
@@ -33,6 +110,61 @@ func main() {
 
 }
 
+// openStore connects to Postgres, unless the connection string is
+// `memory`, which keeps the edges in memory for local development,
+// or a `sqlite://` path to a SQLite database.
+func openStore(databaseURL string) (models.EdgeStore, error) {
+
+	if databaseURL == MEMORY_STORE {
+		return models.NewMemoryStore(), nil
+	}
+
+	if strings.HasPrefix(databaseURL, SQLITE_PREFIX) {
+
+		store, err := models.NewSQLiteStore(strings.TrimPrefix(databaseURL, SQLITE_PREFIX))
+
+		if err != nil {
+			return nil, err
+		}
+
+		migrated, err := store.MigrateEdgeTables()
+
+		if err != nil {
+			store.Close()
+			return nil, fmt.Errorf("could not migrate edge tables: %v", err)
+		}
+
+		if len(migrated) > 0 {
+			log.Printf("Added the qualifier column to %v", migrated)
+		}
+
+		return store, nil
+	}
+
+	// Database connection
+	db := database.InitDB(databaseURL)
+
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("could not ping to database: %v", err)
+	}
+
+	if err := database.CreateSystemTables(db); err != nil {
+		return nil, fmt.Errorf("could not create system tables: %v", err)
+	}
+
+	migrated, err := database.MigrateEdgeTables(db)
+
+	if err != nil {
+		return nil, fmt.Errorf("could not migrate edge tables: %v", err)
+	}
+
+	if len(migrated) > 0 {
+		log.Printf("Added the qualifier column to %v", migrated)
+	}
+
+	return models.NewPostgresStore(db), nil
+}
+
 func env(key, fallbackValue string) string {
 
 	value, isPresent := os.LookupEnv(key)
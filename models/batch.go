@@ -0,0 +1,132 @@
+package models
+
+import (
+	"fmt"
+	"time"
+)
+
+const (
+	SAVE   string = "save"
+	DELETE string = "delete"
+)
+
+// Operation is one write of a batch, a save or a delete of edges of
+// any edge types.
+type Operation struct {
+	Action string  `json:"action"`
+	Edges  *[]Edge `json:"edges"`
+}
+
+// Batch is the JSON model accepted by `/v1/edges/batch`. Stores
+// apply its operations in order and atomically, either every edge
+// is written or none of them.
+type Batch struct {
+	Namespace  *string     `json:"namespace"`
+	Operations []Operation `json:"operations"`
+}
+
+// Validate checks every operation like a save or a delete of its
+// edges is checked, pointing at the fields with their operation.
+func (batch *Batch) Validate(store EdgeStore) error {
+
+	if len(batch.Operations) == 0 {
+		return &ValidationError{"operations", "There has to be atleast one operation in the batch"}
+	}
+
+	for opIdx, operation := range batch.Operations {
+
+		if operation.Action != SAVE && operation.Action != DELETE {
+			return &ValidationError{
+				fmt.Sprintf("operations.%d.action", opIdx),
+				fmt.Sprintf("Operation at %d must have an `action` of save or delete", opIdx),
+			}
+		}
+
+		if operation.Edges == nil || len(*operation.Edges) == 0 {
+			return &ValidationError{
+				fmt.Sprintf("operations.%d.edges", opIdx),
+				fmt.Sprintf("There has to be atleast one edge in the operation at %d", opIdx),
+			}
+		}
+
+		for idx, edge := range *operation.Edges {
+
+			if edge.SrcId == "" || edge.DestId == "" {
+				return &ValidationError{
+					fmt.Sprintf("operations.%d.edges.%d.src_id", opIdx, idx),
+					fmt.Sprintf("Edge at %d does not have `src_id` and `dest_id`", idx),
+				}
+			}
+		}
+
+		var err error
+
+		if operation.Action == SAVE {
+			err = ValidateEdges(store, operation.Edges)
+		} else {
+			err = ValidateDeletes(store, operation.Edges)
+		}
+
+		if err != nil {
+			return prefixFields(err, fmt.Sprintf("operations.%d.", opIdx))
+		}
+	}
+
+	return nil
+}
+
+// SetDefaults qualifies the edge names with the namespace of the
+// batch and sets the defaults of the edges of every operation.
+func (batch *Batch) SetDefaults(updated time.Time) {
+
+	for _, operation := range batch.Operations {
+
+		if operation.Edges == nil {
+			continue
+		}
+
+		ApplyNamespace(batch.Namespace, operation.Edges)
+
+		SetDefaults(operation.Edges, updated)
+	}
+}
+
+// prefixFields points the fields of a validation error at the part
+// of the request the validated edges are in.
+func prefixFields(err error, prefix string) error {
+
+	switch err := err.(type) {
+	case *ValidationError:
+		return &ValidationError{prefix + err.Field, err.Message}
+	case *SchemaError:
+		fields := make([]string, len(err.Fields))
+
+		for idx, field := range err.Fields {
+			fields[idx] = prefix + field
+		}
+
+		return &SchemaError{err.Message, fields}
+	}
+
+	return err
+}
+
+// mirrorOperations adds the mirrored edges to every operation, see
+// MirrorEdges.
+func mirrorOperations(store EdgeStore, operations []Operation) ([]Operation, error) {
+
+	mirrored := make([]Operation, len(operations))
+
+	for idx, operation := range operations {
+
+		edgesPtr, err := MirrorEdges(store, operation.Action, operation.Edges)
+
+		if err != nil {
+			return nil, err
+		}
+
+		mirrored[idx] = Operation{operation.Action, edgesPtr}
+	}
+
+	return mirrored, nil
+}
@@ -0,0 +1,175 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	OUT string = "out"
+	IN  string = "in"
+
+	// Locks the edges of a write, so that the counters & the results
+	// of the write see every state transition exactly once.
+	LOCK_PART string = `
+		SELECT id, COALESCE(status, '') AS status
+		FROM %s
+		WHERE id = ANY($1)
+		FOR UPDATE
+	`
+
+	RETURNING_PART string = `
+		RETURNING id, src_id, dest_id, COALESCE(status, '') AS status
+	`
+
+	COUNTER_UPDATE_PART string = `
+		INSERT INTO %[1]s (node_id, direction, count)
+		SELECT * FROM unnest($1::%[2]s[], $2::varchar[], $3::bigint[])
+		ON CONFLICT (node_id, direction) DO UPDATE
+			SET count = %[1]s.count + EXCLUDED.count
+	`
+
+	COUNTER_SELECT_PART string = `
+		SELECT node_id, count
+		FROM %s
+		WHERE direction = $1 AND node_id = ANY($2)
+	`
+
+	COUNTER_REBUILD_PART string = `
+		INSERT INTO %[2]s (node_id, direction, count)
+			SELECT src_id, 'out', count(*) FROM %[1]s WHERE status = '%[3]s' GROUP BY src_id
+			UNION ALL
+			SELECT dest_id, 'in', count(*) FROM %[1]s WHERE status = '%[3]s' GROUP BY dest_id
+	`
+)
+
+type edgeState struct {
+	Id     string `db:"id"`
+	SrcId  NodeId `db:"src_id"`
+	DestId NodeId `db:"dest_id"`
+	Status string `db:"status"`
+}
+
+type counterKey struct {
+	NodeId    NodeId
+	Direction string
+}
+
+func edgeIds(edges []Edge) []string {
+
+	ids := make([]string, len(edges))
+
+	for idx, edge := range edges {
+		ids[idx] = edge.DbId()
+	}
+
+	return ids
+}
+
+func lockEdgeStates(tx *sqlx.Tx, edgeName string, ids []string) (map[string]string, error) {
+
+	rows := make([]edgeState, 0)
+
+	err := tx.Select(&rows, fmt.Sprintf(LOCK_PART, database.QuoteName(edgeName)), pq.Array(ids))
+
+	if err != nil {
+		return nil, err
+	}
+
+	states := make(map[string]string, len(rows))
+
+	for _, row := range rows {
+		states[row.Id] = row.Status
+	}
+
+	return states, nil
+}
+
+func activeCount(status string) int64 {
+
+	if status == ACTIVE {
+		return 1
+	}
+
+	return 0
+}
+
+// updateCounters applies the active <-> inactive transitions between
+// the locked states and the rows returned by the write. Stale writes
+// return the row unchanged and do not move the counters.
+func updateCounters(tx *sqlx.Tx, edgeName string, idType string, before map[string]string, after []edgeState) error {
+
+	deltas := make(map[counterKey]int64)
+
+	for _, state := range after {
+
+		delta := activeCount(state.Status) - activeCount(before[state.Id])
+
+		if delta == 0 {
+			continue
+		}
+
+		deltas[counterKey{state.SrcId, OUT}] += delta
+		deltas[counterKey{state.DestId, IN}] += delta
+	}
+
+	nodeIds := make([]NodeId, 0, len(deltas))
+	directions := make([]string, 0, len(deltas))
+	counts := make([]int64, 0, len(deltas))
+
+	for key, delta := range deltas {
+
+		if delta == 0 {
+			continue
+		}
+
+		nodeIds = append(nodeIds, key.NodeId)
+		directions = append(directions, key.Direction)
+		counts = append(counts, delta)
+	}
+
+	if len(nodeIds) == 0 {
+		return nil
+	}
+
+	query := fmt.Sprintf(COUNTER_UPDATE_PART, database.QuoteName(database.CountsTableName(edgeName)), idType)
+
+	_, err := tx.Exec(query, pq.Array(nodeIds), pq.Array(directions), pq.Array(counts))
+
+	return err
+}
+
+// RebuildCounts recomputes the counters of an edge type from its
+// table, when they have drifted. Writes to the edge type wait
+// until the rebuild is done.
+func RebuildCounts(db *sqlx.DB, edgeName string) error {
+
+	tx, err := db.Beginx()
+
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	edgeTable := database.QuoteName(edgeName)
+	countsTable := database.QuoteName(database.CountsTableName(edgeName))
+
+	statements := []string{
+		fmt.Sprintf("LOCK TABLE %s IN SHARE MODE", edgeTable),
+		fmt.Sprintf("DELETE FROM %s", countsTable),
+		fmt.Sprintf(COUNTER_REBUILD_PART, edgeTable, countsTable, ACTIVE),
+	}
+
+	for _, statement := range statements {
+
+		if _, err := tx.Exec(statement); err != nil {
+			return err
+		}
+	}
+
+	return tx.Commit()
+}
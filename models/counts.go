@@ -0,0 +1,190 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	COUNT_PART string = `
+		SELECT %[2]s AS node_id, count(*) AS count
+		FROM %[1]s
+		WHERE %[3]s
+		GROUP BY %[2]s
+	`
+)
+
+// CountQuery asks for the out-degree of every `src_id` or the
+// in-degree of every `dest_id`, counting active edges unless other
+// statuses are given.
+type CountQuery struct {
+	Name     *string  `json:"name"`
+	SrcIds   []NodeId `json:"src_id"`
+	DestIds  []NodeId `json:"dest_id"`
+	SrcType  *string  `json:"src_type"`
+	DestType *string  `json:"dest_type"`
+	Status   []string `json:"status"`
+}
+
+type nodeCount struct {
+	NodeId NodeId `db:"node_id"`
+	Count  int64  `db:"count"`
+}
+
+func (query *CountQuery) Validate() error {
+
+	if query.Name == nil || *query.Name == "" {
+		return &ValidationError{"name", "Count must have an edge `name`"}
+	}
+
+	if (len(query.SrcIds) == 0) == (len(query.DestIds) == 0) {
+		return &ValidationError{"src_id", "Count needs either `src_id` or `dest_id` node ids"}
+	}
+
+	if len(query.SrcIds) > MAX_QUERY_LIMIT || len(query.DestIds) > MAX_QUERY_LIMIT {
+		return &ValidationError{"src_id", fmt.Sprintf("Cannot count more than %d nodes at once", MAX_QUERY_LIMIT)}
+	}
+
+	return nil
+}
+
+// nodeColumn is the column grouped by, with the ids to count.
+func (query *CountQuery) nodeColumn() (string, []NodeId) {
+
+	if len(query.SrcIds) > 0 {
+		return "src_id", query.SrcIds
+	}
+
+	return "dest_id", query.DestIds
+}
+
+// countsActive tells if the query only counts active edges, which
+// can be read from the counters of the edge type.
+func (query *CountQuery) countsActive() bool {
+
+	if query.SrcType != nil || query.DestType != nil {
+		return false
+	}
+
+	return len(query.Status) == 0 || (len(query.Status) == 1 && query.Status[0] == ACTIVE)
+}
+
+func (query *CountQuery) ToSQL() (string, []interface{}, error) {
+
+	if err := query.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	column, nodeIds := query.nodeColumn()
+
+	conditions := make([]string, 0)
+	valueArgs := make([]interface{}, 0)
+
+	addCondition := func(condition string, value interface{}) {
+		valueArgs = append(valueArgs, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(valueArgs)))
+	}
+
+	addCondition(column+" = ANY($%d)", pq.Array(nodeIds))
+
+	if query.SrcType != nil {
+		addCondition("src_type = $%d", *query.SrcType)
+	}
+
+	if query.DestType != nil {
+		addCondition("dest_type = $%d", *query.DestType)
+	}
+
+	if len(query.Status) > 0 {
+		addCondition("status = ANY($%d)", pq.Array(query.Status))
+	} else {
+		addCondition("status = $%d", ACTIVE)
+	}
+
+	sql := fmt.Sprintf(COUNT_PART, database.QuoteName(*query.Name), column, strings.Join(conditions, " AND "))
+
+	return sql, valueArgs, nil
+}
+
+func (query *CountQuery) countersSQL() (string, []interface{}) {
+
+	direction := OUT
+
+	column, nodeIds := query.nodeColumn()
+
+	if column == "dest_id" {
+		direction = IN
+	}
+
+	sql := fmt.Sprintf(COUNTER_SELECT_PART, database.QuoteName(database.CountsTableName(*query.Name)))
+
+	return sql, []interface{}{direction, pq.Array(nodeIds)}
+}
+
+// countersOf tells if the edge keeps counters, which registered
+// edge types do when they are created with `counters`. Tables that
+// are not registered keep them when they have a counts table.
+func countersOf(db sqlx.Queryer, edgeType *EdgeType, edgeName string) (bool, error) {
+
+	if edgeType != nil {
+		return edgeType.Options.Counters, nil
+	}
+
+	return database.HasCountsTable(db, edgeName)
+}
+
+// CountEdges returns the edge count of every node in the query,
+// including the nodes that do not have any edges.
+func CountEdges(db sqlx.Queryer, query *CountQuery) (map[NodeId]int64, error) {
+
+	sql, valueArgs, err := query.ToSQL()
+
+	if err != nil {
+		return nil, err
+	}
+
+	edgeType, err := findEdge(lookupEdge(db), *query.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if query.countsActive() {
+
+		hasCounters, err := countersOf(db, edgeType, *query.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if hasCounters {
+			sql, valueArgs = query.countersSQL()
+		}
+	}
+
+	rows := make([]nodeCount, 0)
+
+	err = sqlx.Select(db, &rows, sql, valueArgs...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	_, nodeIds := query.nodeColumn()
+
+	counts := make(map[NodeId]int64, len(nodeIds))
+
+	for _, nodeId := range nodeIds {
+		counts[nodeId] = 0
+	}
+
+	for _, row := range rows {
+		counts[row.NodeId] = row.Count
+	}
+
+	return counts, nil
+}
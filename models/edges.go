@@ -14,19 +14,34 @@ import (
 const (
 	ACTIVE  string = "active"
 	DELETED string = "deleted"
+
+	// Separates the node ids & the qualifier in the id of an edge,
+	// string ids & qualifiers cannot contain it.
+	ID_SEPARATOR string = ":"
+
+	// Postgres binds at most this many parameters to a statement,
+	// writes of more edges are split into statements of up to
+	// MAX_PARAMETERS / parameters of every edge.
+	MAX_PARAMETERS    int = 65535
+	SAVE_PARAMETERS   int = 10
+	DELETE_PARAMETERS int = 8
 )
 
 type Edge struct {
-	Id       string     `json:"id,omitempty" db:"id"`
-	Name     *string    `json:"name" db:"name"`
-	SrcId    int64      `json:"src_id" db:"src_id"`
-	SrcType  *string    `json:"src_type,omitempty" db:"src_type"`
-	DestId   int64      `json:"dest_id" db:"dest_id"`
-	DestType *string    `json:"dest_type,omitempty" db:"dest_type"`
-	Score    float32    `json:"score,omitempty" db:"score,decimal"`
-	Data     *Data      `json:"data,omitempty" db:"data"`
-	Status   string     `json:"status,omitempty" db:"status"`
-	Updated  *time.Time `json:"updated,omitempty" db:"updated,timestamp"`
+	Id        string     `json:"id,omitempty" db:"id"`
+	Name      *string    `json:"name" db:"name"`
+	SrcId     NodeId     `json:"src_id" db:"src_id"`
+	SrcType   *string    `json:"src_type,omitempty" db:"src_type"`
+	DestId    NodeId     `json:"dest_id" db:"dest_id"`
+	DestType  *string    `json:"dest_type,omitempty" db:"dest_type"`
+	Qualifier *string    `json:"qualifier,omitempty" db:"qualifier"`
+	Score     float32    `json:"score,omitempty" db:"score,decimal"`
+	Data      *Data      `json:"data,omitempty" db:"data"`
+	Status    string     `json:"status,omitempty" db:"status"`
+	Updated   *time.Time `json:"updated,omitempty" db:"updated,timestamp"`
+
+	// only written when the stored edge meets the precondition
+	If *Precondition `json:"if,omitempty" db:"-"`
 }
 
 type Data map[string]interface{}
@@ -55,9 +70,16 @@ func (em *Data) Scan(src interface{}) error {
 	return json.Unmarshal(data, em)
 }
 
+// DbId is `src_id:dest_id`, edges of types with qualifiers are told
+// apart by `src_id:dest_id:qualifier`.
 func (edge *Edge) DbId() string {
 
-	id := fmt.Sprintf("%d:%d", edge.SrcId, edge.DestId)
+	id := string(edge.SrcId) + ID_SEPARATOR + string(edge.DestId)
+
+	if edge.Qualifier != nil && *edge.Qualifier != "" {
+		id = id + ID_SEPARATOR + *edge.Qualifier
+	}
+
 	return id
 }
 
@@ -69,6 +91,7 @@ const (
 		  src_type,
 		  dest_id,
 		  dest_type,
+		  qualifier,
 		  score,
 		  data,
 		  status,
@@ -76,95 +99,382 @@ const (
 		)
 		VALUES
 	`
-	// The on conflict part makes sure that slate data
+	// A write replaces the stored edge when it is newer, see Wins.
+	// Edges without `updated` are older than any other edge.
+	WINS_PART string = `
+		%[1]s.updated IS NULL
+		OR %[1]s.updated < EXCLUDED.updated
+		OR (%[1]s.updated = EXCLUDED.updated AND EXCLUDED.status = '%[2]s')
+	`
+
+	// The on conflict part makes sure that stale data
 	// is not updated into DB.
 	UPDATE_PART string = `
 		ON CONFLICT (id) DO UPDATE
 			SET
-				src_type = CASE WHEN %[1]s.updated < EXCLUDED.updated
-					THEN EXCLUDED.src_type
-					ELSE %[1]s.src_type END,
-				dest_type = CASE WHEN %[1]s.updated < EXCLUDED.updated
-					THEN EXCLUDED.dest_type
-					ELSE %[1]s.dest_type END,
-				score = CASE WHEN %[1]s.updated < EXCLUDED.updated
-					THEN EXCLUDED.score
-					ELSE %[1]s.score END,
-				data = CASE WHEN %[1]s.updated < EXCLUDED.updated
-					THEN EXCLUDED.data
-					ELSE %[1]s.data END,
-				status = CASE WHEN %[1]s.updated < EXCLUDED.updated
-					THEN EXCLUDED.status
-					ELSE %[1]s.status END,
-				updated = CASE WHEN %[1]s.updated < EXCLUDED.updated
-					THEN EXCLUDED.updated
-					ELSE %[1]s.updated END
+				src_type = EXCLUDED.src_type,
+				dest_type = EXCLUDED.dest_type,
+				score = EXCLUDED.score,
+				data = EXCLUDED.data,
+				status = EXCLUDED.status,
+				updated = EXCLUDED.updated
+			WHERE %[2]s
+	`
+
+	// Deletes leave a tombstone for edges that were never saved, so
+	// a stale save delivered later does not bring the edge back.
+	DELETE_PART string = `
+		INSERT INTO %s (
+		  id,
+		  src_id,
+		  src_type,
+		  dest_id,
+		  dest_type,
+		  qualifier,
+		  status,
+		  updated
+		)
+		VALUES %s
+		ON CONFLICT (id) DO UPDATE
+			SET
+				status = EXCLUDED.status,
+				updated = EXCLUDED.updated
+			WHERE %s
 	`
-	DELETE_PART = "UPDATE %s SET status=$1, updated=$2 WHERE id IN %s"
 )
 
-func SaveMany(db *sqlx.DB, edgesPtr *[]Edge) error {
+// Wins tells if a write of the edge replaces the stored edge, which
+// is when the write has a later `updated`. Deletes win over saves
+// at the same time, so that every order of delivery of the writes
+// ends with the same edge.
+func (edge *Edge) Wins(current *Edge) bool {
 
-	groupedEdges := GroupByEdgeName(edgesPtr)
+	if current.Updated == nil {
+		return true
+	}
 
-	for edgeName, edges := range groupedEdges {
+	if edge.Updated == nil {
+		return false
+	}
 
-		query := fmt.Sprintf(INSERT_PART, edgeName)
+	if edge.Updated.Equal(*current.Updated) {
+		return edge.Status == DELETED
+	}
 
-		valueStrings := make([]string, 0, len(edges))
-		valueArgs := make([]interface{}, 0, len(edges)*9)
+	return current.Updated.Before(*edge.Updated)
+}
 
-		for idx, edge := range edges {
+// winsPart is the SQL condition of Wins, on the stored edge in the
+// table.
+func winsPart(tableName string) string {
+	return fmt.Sprintf(WINS_PART, tableName, DELETED)
+}
 
-			placeholder := database.GeneratePlaceholder(idx*9+1, 9)
+// SetDefaults makes edges without a status active, and sets the
+// time of writes without `updated`.
+func SetDefaults(edgesPtr *[]Edge, updated time.Time) {
 
-			valueStrings = append(valueStrings, placeholder)
-			valueArgs = append(
-				valueArgs, edge.DbId(), edge.SrcId,
-				edge.SrcType, edge.DestId, edge.DestType, edge.Score,
-				edge.Data, edge.Status, edge.Updated,
-			)
+	edges := *edgesPtr
+
+	for idx := range edges {
+
+		if edges[idx].Status == "" {
+			edges[idx].Status = ACTIVE
 		}
 
-		query = query + strings.Join(valueStrings, " , ")
+		if edges[idx].Updated == nil {
+			edges[idx].Updated = &updated
+		}
+	}
+}
 
-		query = query + fmt.Sprintf(UPDATE_PART, edgeName)
+// chunkEdges splits the edges into chunks that can be written by one
+// statement, with the parameters of every edge.
+func chunkEdges(edges []Edge, parameters int) [][]Edge {
 
-		_, err := db.Exec(query, valueArgs...)
+	size := MAX_PARAMETERS / parameters
 
-		if err != nil {
+	chunks := make([][]Edge, 0, len(edges)/size+1)
+
+	for len(edges) > size {
+		chunks = append(chunks, edges[:size])
+		edges = edges[size:]
+	}
+
+	return append(chunks, edges)
+}
+
+// latestEdges keeps the winning write of every edge that is written
+// more than once, a statement cannot update a row twice.
+func latestEdges(edges []Edge) []Edge {
+
+	latest := make([]Edge, 0, len(edges))
+	positions := make(map[string]int, len(edges))
+
+	for _, edge := range edges {
+
+		idx, ok := positions[edge.DbId()]
+
+		if !ok {
+			positions[edge.DbId()] = len(latest)
+			latest = append(latest, edge)
+		} else if edge.Wins(&latest[idx]) {
+			latest[idx] = edge
+		}
+	}
+
+	return latest
+}
+
+// SaveMany saves the edges of every edge type in one transaction.
+func SaveMany(db *sqlx.DB, edgesPtr *[]Edge) error {
+
+	_, err := ApplyMany(db, []Operation{{SAVE, edgesPtr}})
+
+	return err
+}
+
+// ApplyMany writes the operations in order, in one transaction, and
+// returns the results of the edges of every operation. The edges are
+// locked before their preconditions are checked, so concurrent
+// writes of an edge are serialized.
+func ApplyMany(db *sqlx.DB, operations []Operation) ([][]EdgeResult, error) {
+
+	results := make([][]EdgeResult, len(operations))
+
+	err := inTransaction(db, func(tx *sqlx.Tx) error {
+
+		if err := checkTables(operations, func(edgeName string) (bool, error) {
+			return database.HasTable(tx, edgeName)
+		}); err != nil {
+			return err
+		}
+
+		if err := lockEdges(tx, operations); err != nil {
 			return err
 		}
+
+		if err := checkPreconditions(operations, storedEdges(tx)); err != nil {
+			return err
+		}
+
+		for idx, operation := range operations {
+
+			var err error
+
+			if operation.Action == DELETE {
+				results[idx], err = deleteEdges(tx, operation.Edges)
+			} else {
+				results[idx], err = saveEdges(tx, operation.Edges)
+			}
+
+			if err != nil {
+				return err
+			}
+		}
+
+		return nil
+	})
+
+	if err != nil {
+		return nil, err
 	}
 
-	return nil
+	return results, nil
 }
 
-func DeleteMany(db *sqlx.DB, edgesPtr *[]Edge) error {
+func saveEdges(tx *sqlx.Tx, edgesPtr *[]Edge) ([]EdgeResult, error) {
 
 	groupedEdges := GroupByEdgeName(edgesPtr)
 
+	outcomes := make(map[edgeKey]string, len(*edgesPtr))
+
 	for edgeName, edges := range groupedEdges {
 
-		placeholder := database.GeneratePlaceholder(3, len(edges))
+		for _, chunk := range chunkEdges(latestEdges(edges), SAVE_PARAMETERS) {
 
-		deleteQuery := fmt.Sprintf(DELETE_PART, edgeName, placeholder)
+			query, valueArgs := saveQuery(edgeName, chunk)
 
-		valueArgs := make([]interface{}, 0)
+			err := execWrite(tx, edgeName, SAVE, chunk, query, valueArgs, outcomes)
 
-		valueArgs = append(valueArgs, DELETED, time.Now())
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := FanOut(tx, edgesPtr); err != nil {
+		return nil, err
+	}
+
+	return edgeResults(*edgesPtr, outcomes), nil
+}
+
+// inTransaction commits the writes of fn, or rolls them back when
+// it fails.
+func inTransaction(db *sqlx.DB, fn func(tx *sqlx.Tx) error) error {
+
+	tx, err := db.Beginx()
 
-		for _, edge := range edges {
-			valueArgs = append(valueArgs, edge.DbId())
+	if err != nil {
+		return err
+	}
+
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+func saveQuery(edgeName string, edges []Edge) (string, []interface{}) {
+
+	tableName := database.QuoteName(edgeName)
+
+	query := fmt.Sprintf(INSERT_PART, tableName)
+
+	valueStrings := make([]string, 0, len(edges))
+	valueArgs := make([]interface{}, 0, len(edges)*SAVE_PARAMETERS)
+
+	for idx, edge := range edges {
+
+		placeholder := database.GeneratePlaceholder(idx*SAVE_PARAMETERS+1, SAVE_PARAMETERS)
+
+		valueStrings = append(valueStrings, placeholder)
+		valueArgs = append(
+			valueArgs, edge.DbId(), edge.SrcId,
+			edge.SrcType, edge.DestId, edge.DestType, edge.Qualifier,
+			edge.Score, edge.Data, edge.Status, edge.Updated,
+		)
+	}
+
+	query = query + strings.Join(valueStrings, " , ")
+
+	query = query + fmt.Sprintf(UPDATE_PART, tableName, winsPart(tableName))
+
+	return query, valueArgs
+}
+
+// DeleteMany deletes the edges of every edge type in one transaction.
+func DeleteMany(db *sqlx.DB, edgesPtr *[]Edge) error {
+
+	_, err := ApplyMany(db, []Operation{{DELETE, edgesPtr}})
+
+	return err
+}
+
+func deleteEdges(tx *sqlx.Tx, edgesPtr *[]Edge) ([]EdgeResult, error) {
+
+	deleted := deletedEdges(*edgesPtr)
+
+	outcomes := make(map[edgeKey]string, len(deleted))
+
+	for edgeName, edges := range GroupByEdgeName(&deleted) {
+
+		for _, chunk := range chunkEdges(latestEdges(edges), DELETE_PARAMETERS) {
+
+			query, valueArgs := deleteQuery(edgeName, chunk)
+
+			err := execWrite(tx, edgeName, DELETE, chunk, query, valueArgs, outcomes)
+
+			if err != nil {
+				return nil, err
+			}
 		}
+	}
 
-		_, err := db.Exec(deleteQuery, valueArgs...)
+	if err := FanOut(tx, edgesPtr); err != nil {
+		return nil, err
+	}
 
-		if err != nil {
+	return edgeResults(deleted, outcomes), nil
+}
+
+// deletedEdges are the tombstones written by a delete.
+func deletedEdges(edges []Edge) []Edge {
+
+	deleted := make([]Edge, len(edges))
+
+	for idx, edge := range edges {
+		deleted[idx] = edge
+		deleted[idx].Status = DELETED
+	}
+
+	return deleted
+}
+
+func deleteQuery(edgeName string, edges []Edge) (string, []interface{}) {
+
+	valueStrings := make([]string, 0, len(edges))
+	valueArgs := make([]interface{}, 0, len(edges)*DELETE_PARAMETERS)
+
+	for idx, edge := range edges {
+
+		placeholder := database.GeneratePlaceholder(idx*DELETE_PARAMETERS+1, DELETE_PARAMETERS)
+
+		valueStrings = append(valueStrings, placeholder)
+		valueArgs = append(
+			valueArgs, edge.DbId(), edge.SrcId, edge.SrcType,
+			edge.DestId, edge.DestType, edge.Qualifier, edge.Status, edge.Updated,
+		)
+	}
+
+	tableName := database.QuoteName(edgeName)
+
+	query := fmt.Sprintf(
+		DELETE_PART, tableName, strings.Join(valueStrings, " , "), winsPart(tableName),
+	)
+
+	return query, valueArgs
+}
+
+// execWrite runs a write on one edge type, keeping its counters up
+// to date when the edge type has them, and adds the outcome of
+// every edge to outcomes. The edges of a write are locked first, to
+// tell the states they were in before the write, and the write
+// returns the edges that it replaced.
+func execWrite(tx *sqlx.Tx, edgeName string, action string, edges []Edge, query string, valueArgs []interface{}, outcomes map[edgeKey]string) error {
+
+	before, err := lockEdgeStates(tx, edgeName, edgeIds(edges))
+
+	if err != nil {
+		return err
+	}
+
+	after := make([]edgeState, 0, len(edges))
+
+	if err := tx.Select(&after, query+RETURNING_PART, valueArgs...); err != nil {
+		return err
+	}
+
+	countsIdType, err := database.CountsIdType(tx, edgeName)
+
+	if err != nil {
+		return err
+	}
+
+	if countsIdType != "" {
+
+		if err := updateCounters(tx, edgeName, countsIdType, before, after); err != nil {
 			return err
 		}
 	}
 
+	written := make(map[string]bool, len(after))
+
+	for _, state := range after {
+		written[state.Id] = true
+	}
+
+	for _, edge := range edges {
+
+		status, existed := before[edge.DbId()]
+
+		outcomes[edgeKey{edgeName, edge.DbId()}] = writeOutcome(action, existed, status, written[edge.DbId()])
+	}
+
 	return nil
 }
 
@@ -181,6 +491,27 @@ func RunQuery(db *sqlx.DB, query string) (*[]Edge, error) {
 	return &edgeList, nil
 }
 
+// ApplyNamespace qualifies the names of edges that are not already
+// addressed as `namespace.edge_name`.
+func ApplyNamespace(namespace *string, edgesPtr *[]Edge) {
+
+	if namespace == nil || *namespace == "" {
+		return
+	}
+
+	edges := *edgesPtr
+
+	for idx, edge := range edges {
+
+		if edge.Name == nil || strings.Contains(*edge.Name, ".") {
+			continue
+		}
+
+		qualifiedName := database.QualifyName(*namespace, *edge.Name)
+		edges[idx].Name = &qualifiedName
+	}
+}
+
 func GroupByEdgeName(edgesPtr *[]Edge) map[string][]Edge {
 
 	allEdges := *edgesPtr
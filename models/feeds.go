@@ -0,0 +1,441 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	DEFAULT_FANOUT_LIMIT int = 1000
+
+	FEED_SAVE_PART string = `
+		INSERT INTO loki_feeds (name, content_edge, follow_edge, fanout_limit)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (name) DO UPDATE
+			SET
+				content_edge = EXCLUDED.content_edge,
+				follow_edge = EXCLUDED.follow_edge,
+				fanout_limit = EXCLUDED.fanout_limit
+	`
+
+	FEED_SELECT_PART string = `
+		SELECT name, content_edge, follow_edge, fanout_limit
+		FROM loki_feeds
+	`
+
+	// Pushes the saved items to the inbox of every follower of
+	// their author, reading the items back so stale saves are not
+	// fanned out.
+	FANOUT_PUSH_PART string = `
+		INSERT INTO %[1]s (user_id, edge_id, score)
+		SELECT DISTINCT f.src_id, c.id, c.score
+		FROM %[2]s c
+		JOIN %[3]s f ON f.dest_id = c.src_id AND f.status = '%[4]s'
+		WHERE c.id = ANY($1) AND c.status = '%[4]s'
+		ON CONFLICT (user_id, edge_id) DO UPDATE
+			SET score = EXCLUDED.score
+	`
+
+	FANOUT_REMOVE_PART string = `
+		DELETE FROM %[1]s
+		WHERE edge_id = ANY($1)
+		  AND edge_id NOT IN (SELECT id FROM %[2]s WHERE id = ANY($1) AND status = '%[3]s')
+	`
+
+	FANOUT_PULL_PART string = `
+		INSERT INTO %s (author_id)
+		SELECT unnest($1::bigint[])
+		ON CONFLICT DO NOTHING
+	`
+
+	// Authors back under the fan-out limit are no longer pulled on
+	// read, their items are pushed to the inbox of every follower.
+	FANOUT_UNPULL_PART string = `
+		WITH removed AS (
+		  DELETE FROM %[4]s WHERE author_id = ANY($1::bigint[])
+		  RETURNING author_id
+		)
+		INSERT INTO %[1]s (user_id, edge_id, score)
+		SELECT DISTINCT f.src_id, c.id, c.score
+		FROM %[2]s c
+		JOIN removed r ON r.author_id = c.src_id
+		JOIN %[3]s f ON f.dest_id = c.src_id AND f.status = '%[5]s'
+		WHERE c.status = '%[5]s'
+		ON CONFLICT (user_id, edge_id) DO UPDATE
+			SET score = EXCLUDED.score
+	`
+
+	// Removes the items of unfollowed authors from the inbox of
+	// the user, unless the user still follows them on another edge.
+	UNFOLLOW_REMOVE_PART string = `
+		DELETE FROM %[1]s i
+		USING %[2]s c, %[3]s f
+		WHERE f.id = ANY($1) AND f.status != '%[4]s'
+		  AND i.user_id = f.src_id AND i.edge_id = c.id AND c.src_id = f.dest_id
+		  AND NOT EXISTS (
+		    SELECT 1 FROM %[3]s a
+		    WHERE a.src_id = f.src_id AND a.dest_id = f.dest_id AND a.status = '%[4]s'
+		  )
+	`
+
+	// Pushes the newest items of the followed authors to the inbox
+	// of the user, unless the authors are pulled on read.
+	FOLLOW_BACKFILL_PART string = `
+		INSERT INTO %[1]s (user_id, edge_id, score)
+		SELECT DISTINCT f.src_id, c.id, c.score
+		FROM %[3]s f
+		CROSS JOIN LATERAL (
+		  SELECT id, score FROM %[2]s
+		  WHERE src_id = f.dest_id AND status = '%[5]s' AND score IS NOT NULL
+		  ORDER BY score DESC, id DESC
+		  LIMIT $2
+		) c
+		WHERE f.id = ANY($1) AND f.status = '%[5]s'
+		  AND NOT EXISTS (SELECT 1 FROM %[4]s p WHERE p.author_id = f.dest_id)
+		ON CONFLICT (user_id, edge_id) DO UPDATE
+			SET score = EXCLUDED.score
+	`
+
+	// Merges the newest inbox items with the newest items of the
+	// followed authors that are pulled on read. Inbox items are only
+	// read while the user follows their author.
+	FEED_READ_PART string = `
+		WHERE status = '%[8]s' AND score IS NOT NULL %[4]s AND id IN (
+		  (
+		    SELECT i.edge_id FROM %[2]s i
+		    JOIN %[1]s c ON c.id = i.edge_id
+		    WHERE i.user_id = $1 AND i.score IS NOT NULL %[5]s
+		      AND EXISTS (
+		        SELECT 1 FROM %[3]s f
+		        WHERE f.src_id = i.user_id AND f.dest_id = c.src_id AND f.status = '%[8]s'
+		      )
+		    ORDER BY i.score DESC, i.edge_id DESC
+		    LIMIT $2
+		  )
+		  UNION ALL
+		  (
+		    SELECT c.id FROM %[1]s c
+		    JOIN %[3]s f ON f.dest_id = c.src_id AND f.src_id = $1 AND f.status = '%[8]s'
+		    JOIN %[6]s p ON p.author_id = c.src_id
+		    WHERE c.status = '%[8]s' AND c.score IS NOT NULL %[7]s
+		    ORDER BY c.score DESC, c.id DESC
+		    LIMIT $2
+		  )
+		)
+		ORDER BY score DESC, id DESC
+		LIMIT $2
+	`
+
+	FEED_CURSOR_PART string = "AND (%[1]sscore, %[1]s%[2]s) < ($3::decimal, $4)"
+)
+
+// Feed is a timeline of `content_edge` items (author -> item), from
+// every author a user follows on `follow_edge` (user -> author).
+// Items of authors with up to `fanout_limit` followers are pushed
+// to the inboxes of their followers on save, items of the others
+// are merged in when the feed is read.
+type Feed struct {
+	Name        string `json:"name" db:"name"`
+	ContentEdge string `json:"content_edge" db:"content_edge"`
+	FollowEdge  string `json:"follow_edge" db:"follow_edge"`
+	FanoutLimit int    `json:"fanout_limit" db:"fanout_limit"`
+}
+
+func (feed *Feed) Validate() error {
+
+	if feed.Name == "" {
+		return &ValidationError{"name", "Name is required to initialize the feed"}
+	}
+
+	if !database.ValidTableName(feed.Name) {
+		return &ValidationError{"name", NAME_GRAMMAR_MESSAGE}
+	}
+
+	if feed.ContentEdge == "" {
+		return &ValidationError{"content_edge", "Feed must have a `content_edge`"}
+	}
+
+	if feed.FollowEdge == "" {
+		return &ValidationError{"follow_edge", "Feed must have a `follow_edge`"}
+	}
+
+	if feed.FanoutLimit < 0 {
+		return &ValidationError{"fanout_limit", "Fanout limit cannot be negative"}
+	}
+
+	return nil
+}
+
+// validateEdges checks the content & follow edges of the feed exist,
+// with the integer node ids inboxes are keyed by.
+func (feed *Feed) validateEdges(lookup edgeLookup) error {
+
+	for _, field := range []struct {
+		name     string
+		edgeName string
+	}{
+		{"content_edge", feed.ContentEdge},
+		{"follow_edge", feed.FollowEdge},
+	} {
+
+		edgeType, exists, err := lookup(field.edgeName)
+
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return &UnknownEdgeError{field.edgeName}
+		}
+
+		if edgeType != nil && edgeType.Options.IdColumnType() != ID_COLUMN_TYPES[INT_ID] {
+			return &ValidationError{field.name, fmt.Sprintf("Feeds need edges with int node ids, %s has %s ids", field.edgeName, edgeType.Options.IdType)}
+		}
+	}
+
+	return nil
+}
+
+func CreateFeed(db *sqlx.DB, feed *Feed) error {
+
+	if err := feed.Validate(); err != nil {
+		return err
+	}
+
+	if feed.FanoutLimit == 0 {
+		feed.FanoutLimit = DEFAULT_FANOUT_LIMIT
+	}
+
+	if err := feed.validateEdges(lookupEdge(db)); err != nil {
+		return err
+	}
+
+	if err := database.CreateFeedTables(db, feed.Name); err != nil {
+		return err
+	}
+
+	_, err := db.Exec(FEED_SAVE_PART, feed.Name, feed.ContentEdge, feed.FollowEdge, feed.FanoutLimit)
+
+	return err
+}
+
+// GetFeed returns nil when there is no feed with the name.
+func GetFeed(db *sqlx.DB, feedName string) (*Feed, error) {
+
+	var feed Feed
+
+	err := db.Get(&feed, FEED_SELECT_PART+" WHERE name = $1", feedName)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &feed, nil
+}
+
+// FanOut updates the inboxes of the feeds that have the saved or
+// deleted edges as their items or their follows.
+func FanOut(db sqlx.Ext, edgesPtr *[]Edge) error {
+
+	groupedEdges := GroupByEdgeName(edgesPtr)
+
+	edgeNames := make([]string, 0, len(groupedEdges))
+
+	for edgeName := range groupedEdges {
+		edgeNames = append(edgeNames, edgeName)
+	}
+
+	feeds := make([]Feed, 0)
+
+	err := sqlx.Select(db, &feeds, FEED_SELECT_PART+" WHERE content_edge = ANY($1) OR follow_edge = ANY($1)", pq.Array(edgeNames))
+
+	if err != nil {
+		return err
+	}
+
+	for _, feed := range feeds {
+
+		if items, ok := groupedEdges[feed.ContentEdge]; ok {
+
+			if err := fanOutFeed(db, &feed, items); err != nil {
+				return err
+			}
+		}
+
+		if follows, ok := groupedEdges[feed.FollowEdge]; ok {
+
+			if err := followFeed(db, &feed, follows); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// followFeed pushes the newest items of the authors of the saved
+// follows, up to MAX_QUERY_LIMIT, the largest page of a feed, to the
+// inboxes of their users, and removes the items of the authors of
+// the deleted follows.
+func followFeed(db sqlx.Ext, feed *Feed, follows []Edge) error {
+
+	inboxTable := database.QuoteName(database.FeedInboxTableName(feed.Name))
+	contentTable := database.QuoteName(feed.ContentEdge)
+	followTable := database.QuoteName(feed.FollowEdge)
+	pullTable := database.QuoteName(database.FeedPullTableName(feed.Name))
+
+	followIds := pq.Array(edgeIds(follows))
+
+	query := fmt.Sprintf(FOLLOW_BACKFILL_PART, inboxTable, contentTable, followTable, pullTable, ACTIVE)
+
+	if _, err := db.Exec(query, followIds, MAX_QUERY_LIMIT); err != nil {
+		return err
+	}
+
+	query = fmt.Sprintf(UNFOLLOW_REMOVE_PART, inboxTable, contentTable, followTable, ACTIVE)
+
+	_, err := db.Exec(query, followIds)
+
+	return err
+}
+
+func fanOutFeed(db sqlx.Ext, feed *Feed, items []Edge) error {
+
+	authorIds := make([]NodeId, 0, len(items))
+
+	for _, item := range items {
+		authorIds = append(authorIds, item.SrcId)
+	}
+
+	followerCounts, err := CountEdges(db, &CountQuery{
+		Name:    &feed.FollowEdge,
+		DestIds: authorIds,
+	})
+
+	if err != nil {
+		return err
+	}
+
+	pushIds := make([]string, 0, len(items))
+	pushAuthorIds := make([]NodeId, 0, len(items))
+	pullAuthorIds := make([]NodeId, 0)
+
+	for _, item := range items {
+
+		if followerCounts[item.SrcId] > int64(feed.FanoutLimit) {
+			pullAuthorIds = append(pullAuthorIds, item.SrcId)
+		} else {
+			pushIds = append(pushIds, item.DbId())
+			pushAuthorIds = append(pushAuthorIds, item.SrcId)
+		}
+	}
+
+	inboxTable := database.QuoteName(database.FeedInboxTableName(feed.Name))
+	contentTable := database.QuoteName(feed.ContentEdge)
+	followTable := database.QuoteName(feed.FollowEdge)
+	pullTable := database.QuoteName(database.FeedPullTableName(feed.Name))
+
+	statements := []struct {
+		query string
+		arg   interface{}
+	}{
+		{fmt.Sprintf(FANOUT_PULL_PART, pullTable), pq.Array(pullAuthorIds)},
+		{fmt.Sprintf(FANOUT_UNPULL_PART, inboxTable, contentTable, followTable, pullTable, ACTIVE), pq.Array(pushAuthorIds)},
+		{fmt.Sprintf(FANOUT_PUSH_PART, inboxTable, contentTable, followTable, ACTIVE), pq.Array(pushIds)},
+		{fmt.Sprintf(FANOUT_REMOVE_PART, inboxTable, contentTable, ACTIVE), pq.Array(edgeIds(items))},
+	}
+
+	for _, statement := range statements {
+
+		if _, err := db.Exec(statement.query, statement.arg); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReadFeed returns a page of the user's feed, newest (highest score)
+// items first, and the cursor for the next page.
+func ReadFeed(db *sqlx.DB, feed *Feed, userId int64, limit int, cursorValue string) (*[]Edge, string, error) {
+
+	if limit < 0 || limit > MAX_QUERY_LIMIT {
+		return nil, "", &ValidationError{"limit", fmt.Sprintf("Limit cannot be negative or more than %d", MAX_QUERY_LIMIT)}
+	}
+
+	if limit == 0 {
+		limit = DEFAULT_QUERY_LIMIT
+	}
+
+	valueArgs := []interface{}{userId, limit + 1}
+	cursorParts := []string{"", "", ""}
+
+	if cursorValue != "" {
+		cursor, err := DecodeCursor(cursorValue)
+
+		if err != nil || cursor.OrderBy != "-score" {
+			return nil, "", &ValidationError{"cursor", "Cursor is invalid or was not issued for a feed"}
+		}
+
+		valueArgs = append(valueArgs, cursor.Key, cursor.Id)
+
+		cursorParts = []string{
+			fmt.Sprintf(FEED_CURSOR_PART, "", "id"),
+			fmt.Sprintf(FEED_CURSOR_PART, "i.", "edge_id"),
+			fmt.Sprintf(FEED_CURSOR_PART, "c.", "id"),
+		}
+	}
+
+	contentTable := database.QuoteName(feed.ContentEdge)
+
+	query := fmt.Sprintf(SELECT_PART, contentTable, "score") + fmt.Sprintf(
+		FEED_READ_PART,
+		contentTable,
+		database.QuoteName(database.FeedInboxTableName(feed.Name)),
+		database.QuoteName(feed.FollowEdge),
+		cursorParts[0],
+		cursorParts[1],
+		database.QuoteName(database.FeedPullTableName(feed.Name)),
+		cursorParts[2],
+		ACTIVE,
+	)
+
+	rows := make([]cursorEdge, 0)
+
+	if err := db.Select(&rows, query, valueArgs...); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+
+	if len(rows) > limit {
+		rows = rows[:limit]
+
+		last := rows[len(rows)-1]
+
+		cursor := Cursor{
+			OrderBy: "-score",
+			Key:     last.CursorKey,
+			Id:      last.Id,
+		}
+
+		nextCursor = cursor.Encode()
+	}
+
+	items := make([]Edge, len(rows))
+
+	for idx, row := range rows {
+		items[idx] = row.Edge
+		items[idx].Name = &feed.ContentEdge
+	}
+
+	return &items, nextCursor, nil
+}
@@ -0,0 +1,177 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/sonnes/loki/database"
+)
+
+// InverseName is the full name of the inverse of an edge type. An
+// inverse without a namespace is in the namespace of the edge type.
+func (edgeType *EdgeType) InverseName() string {
+
+	inverse := edgeType.Options.Inverse
+
+	if inverse == "" || strings.Contains(inverse, ".") {
+		return inverse
+	}
+
+	return database.QualifyName(edgeType.Namespace, inverse)
+}
+
+// Mirror is the edge in the opposite direction, on the edge type
+// the edge is mirrored to.
+func (edge *Edge) Mirror(edgeName string) Edge {
+
+	mirrored := *edge
+
+	mirrored.Name = &edgeName
+	mirrored.SrcId, mirrored.DestId = edge.DestId, edge.SrcId
+	mirrored.SrcType, mirrored.DestType = edge.DestType, edge.SrcType
+	mirrored.If = nil
+
+	return mirrored
+}
+
+// MirrorEdges adds the mirrored edge of every edge of a symmetric
+// type or a type with an inverse, for the store to write them along
+// with the edges. Mirrored edges are not mirrored again, and are
+// validated for the action against their own edge type, with errors
+// at the edge they mirror.
+func MirrorEdges(store EdgeStore, action string, edgesPtr *[]Edge) (*[]Edge, error) {
+
+	edgeTypes, err := edgeTypesOf(store, edgesPtr)
+
+	if err != nil {
+		return nil, err
+	}
+
+	mirrored := make([]Edge, 0, len(*edgesPtr))
+	sources := make([]int, 0, len(*edgesPtr))
+
+	for idx, edge := range *edgesPtr {
+
+		edgeType := edgeTypes[*edge.Name]
+
+		if edgeType == nil {
+			continue
+		}
+
+		if edgeType.Options.Symmetric {
+			mirrored = append(mirrored, edge.Mirror(*edge.Name))
+			sources = append(sources, idx)
+		} else if edgeType.Options.Inverse != "" {
+			mirrored = append(mirrored, edge.Mirror(edgeType.InverseName()))
+			sources = append(sources, idx)
+		}
+	}
+
+	if len(mirrored) == 0 {
+		return edgesPtr, nil
+	}
+
+	if action == DELETE {
+		err = validateDeletes(store, &mirrored, sources)
+	} else {
+		err = validateEdges(store, &mirrored, sources)
+	}
+
+	if err != nil {
+		return nil, mirrorError(err)
+	}
+
+	allEdges := append(append(make([]Edge, 0, len(*edgesPtr)+len(mirrored)), *edgesPtr...), mirrored...)
+
+	return &allEdges, nil
+}
+
+// mirrorError tells that a validation error is of a mirrored edge,
+// which the edge it mirrors is valid without.
+func mirrorError(err error) error {
+
+	switch err := err.(type) {
+	case *ValidationError:
+		return &ValidationError{err.Field, "Mirrored edge is invalid: " + err.Message}
+	case *SchemaError:
+		return &SchemaError{"Mirrored edge is invalid: " + err.Message, err.Fields}
+	}
+
+	return err
+}
+
+// InverseType is the edge type to create along with an edge type
+// that has an `inverse`, or nil when it has none. An inverse type
+// that exists keeps its definition and is made to mirror the edge
+// type, unless it already mirrors another one. A new inverse type
+// connects the nodes of the edge type the other way around.
+func InverseType(store EdgeStore, edgeType *EdgeType) (*EdgeType, error) {
+
+	if edgeType.Options.Inverse == "" {
+		return nil, nil
+	}
+
+	if edgeType.Options.Symmetric {
+		return nil, &ValidationError{"inverse", "A symmetric edge is its own inverse"}
+	}
+
+	inverseName := edgeType.InverseName()
+
+	if !database.ValidTableName(inverseName) {
+		return nil, &ValidationError{"inverse", NAME_GRAMMAR_MESSAGE}
+	}
+
+	if inverseName == edgeType.FullName() {
+		return nil, &ValidationError{"inverse", "Edge cannot be its own inverse, make it `symmetric`"}
+	}
+
+	current, err := store.GetType(inverseName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if current == nil {
+
+		namespace, name := database.SplitName(inverseName)
+
+		inverseType := *edgeType
+
+		inverseType.Namespace = namespace
+		inverseType.Name = name
+		inverseType.SrcTypes = edgeType.DestTypes
+		inverseType.DestTypes = edgeType.SrcTypes
+		inverseType.Options.Counters = false
+		inverseType.Options.Inverse = edgeType.FullName()
+		inverseType.Created = nil
+		inverseType.Stats = nil
+
+		return &inverseType, nil
+	}
+
+	if current.Options.Inverse != "" && current.InverseName() != edgeType.FullName() {
+		return nil, &ValidationError{
+			"inverse",
+			fmt.Sprintf("%s - edge is already the inverse of %s", inverseName, current.InverseName()),
+		}
+	}
+
+	if current.Options.IdColumnType() != edgeType.Options.IdColumnType() {
+		return nil, &ValidationError{
+			"inverse",
+			fmt.Sprintf("%s - edge has %s node ids", inverseName, current.Options.IdColumnType()),
+		}
+	}
+
+	if current.Options.Qualified != edgeType.Options.Qualified {
+		return nil, &ValidationError{
+			"inverse",
+			fmt.Sprintf("%s - edge must be qualified like %s", inverseName, edgeType.FullName()),
+		}
+	}
+
+	current.Options.Inverse = edgeType.FullName()
+	current.Stats = nil
+
+	return current, nil
+}
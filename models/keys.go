@@ -0,0 +1,294 @@
+package models
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	READ  string = "read"
+	WRITE string = "write"
+	ADMIN string = "admin"
+
+	// Scopes on every edge type, `*`, and on every edge type of a
+	// namespace, `namespace.*`.
+	ALL_EDGES string = "*"
+
+	KEY_SAVE_PART string = `
+		INSERT INTO loki_api_keys (id, name, secret_hash, scopes)
+		VALUES ($1, $2, $3, $4)
+		RETURNING created
+	`
+
+	KEY_SELECT_PART string = `
+		SELECT id, name, secret_hash, scopes, created, revoked
+		FROM loki_api_keys
+	`
+
+	KEY_REVOKE_PART string = `
+		UPDATE loki_api_keys SET revoked = now()
+		WHERE id = $1 AND revoked IS NULL
+	`
+)
+
+// Actions a scope can grant. `delete` is apart from `write`, so keys
+// can save edges without deleting them, `admin` initializes edges.
+var KEY_ACTIONS = []string{READ, WRITE, DELETE, ADMIN}
+
+// Scope grants the actions on the edge types matching any of the
+// edge names, see ALL_EDGES.
+type Scope struct {
+	Edges   []string `json:"edges"`
+	Actions []string `json:"actions"`
+}
+
+type Scopes []Scope
+
+func (scopes Scopes) Value() (driver.Value, error) {
+	return json.Marshal(scopes)
+}
+
+func (scopes *Scopes) Scan(src interface{}) error {
+
+	var data []byte
+	if b, ok := src.([]byte); ok {
+		data = b
+	} else if s, ok := src.(string); ok {
+		data = []byte(s)
+	} else if src == nil {
+		return nil
+	}
+	return json.Unmarshal(data, scopes)
+}
+
+// ApiKey is a key to the API, with the scopes it is allowed. Only a
+// hash of the secret of a key is stored, the secret is handed out
+// once when the key is minted, as the token `id.secret`.
+type ApiKey struct {
+	Id         string     `json:"id" db:"id"`
+	Name       string     `json:"name" db:"name"`
+	SecretHash string     `json:"-" db:"secret_hash"`
+	Scopes     Scopes     `json:"scopes" db:"scopes"`
+	Created    *time.Time `json:"created,omitempty" db:"created"`
+	Revoked    *time.Time `json:"revoked,omitempty" db:"revoked"`
+}
+
+func (key *ApiKey) Validate() error {
+
+	if key.Name == "" {
+		return &ValidationError{"name", "Name is required to mint a key"}
+	}
+
+	if len(key.Scopes) == 0 {
+		return &ValidationError{"scopes", "Key must have atleast one scope"}
+	}
+
+	for idx, scope := range key.Scopes {
+
+		if len(scope.Edges) == 0 {
+			return &ValidationError{fmt.Sprintf("scopes.%d.edges", idx), fmt.Sprintf("Scope at %d must have `edges`", idx)}
+		}
+
+		for _, edgeName := range scope.Edges {
+
+			pattern := strings.TrimSuffix(edgeName, ".*")
+
+			if edgeName != ALL_EDGES && !database.ValidTableName(pattern) {
+				return &ValidationError{fmt.Sprintf("scopes.%d.edges", idx), NAME_GRAMMAR_MESSAGE}
+			}
+		}
+
+		if len(scope.Actions) == 0 {
+			return &ValidationError{fmt.Sprintf("scopes.%d.actions", idx), fmt.Sprintf("Scope at %d must have `actions`", idx)}
+		}
+
+		for _, action := range scope.Actions {
+			if !containsString(KEY_ACTIONS, &action) {
+				return &ValidationError{
+					fmt.Sprintf("scopes.%d.actions", idx),
+					fmt.Sprintf("Actions must be one of %v", KEY_ACTIONS),
+				}
+			}
+		}
+	}
+
+	return nil
+}
+
+// Allows tells if any scope of the key grants the action on the
+// edge type.
+func (key *ApiKey) Allows(action string, edgeName string) bool {
+
+	for _, scope := range key.Scopes {
+
+		if !containsString(scope.Actions, &action) {
+			continue
+		}
+
+		for _, pattern := range scope.Edges {
+			if matchesEdge(pattern, edgeName) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// IsAdmin tells if the key administers every edge type, which lets
+// it mint keys, create feeds & run SQL.
+func (key *ApiKey) IsAdmin() bool {
+	return key.Allows(ADMIN, ALL_EDGES)
+}
+
+func matchesEdge(pattern string, edgeName string) bool {
+
+	if pattern == ALL_EDGES || pattern == edgeName {
+		return true
+	}
+
+	namespace, _ := database.SplitName(edgeName)
+
+	return namespace != "" && pattern == namespace+".*"
+}
+
+// MintKey gives the key an id & the hash of a new secret, and
+// returns the token of the key.
+func MintKey(key *ApiKey) (string, error) {
+
+	id, err := randomHex(8)
+
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := randomHex(32)
+
+	if err != nil {
+		return "", err
+	}
+
+	key.Id = id
+	key.SecretHash = hashSecret(secret)
+
+	return id + "." + secret, nil
+}
+
+// Matches tells if the token is of the key & the key is not revoked.
+func (key *ApiKey) Matches(token string) bool {
+
+	_, secret := splitToken(token)
+
+	hash := hashSecret(secret)
+
+	return key.Revoked == nil && subtle.ConstantTimeCompare([]byte(hash), []byte(key.SecretHash)) == 1
+}
+
+// TokenId is the id of the key a token is of.
+func TokenId(token string) string {
+
+	id, _ := splitToken(token)
+
+	return id
+}
+
+func splitToken(token string) (string, string) {
+
+	if idx := strings.Index(token, "."); idx >= 0 {
+		return token[:idx], token[idx+1:]
+	}
+
+	return "", token
+}
+
+func hashSecret(secret string) string {
+
+	hash := sha256.Sum256([]byte(secret))
+
+	return hex.EncodeToString(hash[:])
+}
+
+func randomHex(size int) (string, error) {
+
+	data := make([]byte, size)
+
+	if _, err := rand.Read(data); err != nil {
+		return "", err
+	}
+
+	return hex.EncodeToString(data), nil
+}
+
+func CreateKey(db *sqlx.DB, key *ApiKey) (string, error) {
+
+	token, err := MintKey(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	err = db.Get(&key.Created, KEY_SAVE_PART, key.Id, key.Name, key.SecretHash, key.Scopes)
+
+	if err != nil {
+		return "", err
+	}
+
+	return token, nil
+}
+
+// GetKey returns nil when there is no key with the id.
+func GetKey(db *sqlx.DB, id string) (*ApiKey, error) {
+
+	var key ApiKey
+
+	err := db.Get(&key, KEY_SELECT_PART+" WHERE id = $1", id)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
+
+func ListKeys(db *sqlx.DB) (*[]ApiKey, error) {
+
+	keys := make([]ApiKey, 0)
+
+	err := db.Select(&keys, KEY_SELECT_PART+" ORDER BY created, id")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &keys, nil
+}
+
+// RevokeKey tells if there was a key with the id that was not
+// revoked yet.
+func RevokeKey(db *sqlx.DB, id string) (bool, error) {
+
+	res, err := db.Exec(KEY_REVOKE_PART, id)
+
+	if err != nil {
+		return false, err
+	}
+
+	revoked, err := res.RowsAffected()
+
+	return revoked > 0, err
+}
@@ -0,0 +1,934 @@
+package models
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MemoryStore keeps edges in maps, for tests and local development.
+// Writes resolve conflicts like UPDATE_PART, with Edge.Wins.
+type MemoryStore struct {
+	lock  sync.RWMutex
+	types map[string]*EdgeType
+	edges map[string]map[string]*Edge
+	keys  map[string]*ApiKey
+}
+
+var (
+	_ EdgeStore   = (*MemoryStore)(nil)
+	_ KeyStore    = (*MemoryStore)(nil)
+	_ Traverser   = (*MemoryStore)(nil)
+	_ SetCombiner = (*MemoryStore)(nil)
+)
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		types: make(map[string]*EdgeType),
+		edges: make(map[string]map[string]*Edge),
+		keys:  make(map[string]*ApiKey),
+	}
+}
+
+func (store *MemoryStore) Close() error {
+	return nil
+}
+
+func (store *MemoryStore) CreateType(edgeType *EdgeType) error {
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	edgeName := edgeType.FullName()
+
+	registered := *edgeType
+	registered.Stats = nil
+
+	if current, ok := store.types[edgeName]; ok {
+		registered.Created = current.Created
+	} else {
+		created := time.Now()
+		registered.Created = &created
+	}
+
+	store.types[edgeName] = &registered
+
+	if _, ok := store.edges[edgeName]; !ok {
+		store.edges[edgeName] = make(map[string]*Edge)
+	}
+
+	return nil
+}
+
+func (store *MemoryStore) GetType(edgeName string) (*EdgeType, error) {
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	edgeType, ok := store.types[edgeName]
+
+	if !ok {
+		return nil, nil
+	}
+
+	found := *edgeType
+
+	return &found, nil
+}
+
+func (store *MemoryStore) ListTypes() (*[]EdgeType, error) {
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	edgeTypes := make([]EdgeType, 0, len(store.types))
+
+	for _, edgeType := range store.types {
+		edgeTypes = append(edgeTypes, *edgeType)
+	}
+
+	sort.Slice(edgeTypes, func(i, j int) bool {
+		return edgeTypes[i].FullName() < edgeTypes[j].FullName()
+	})
+
+	return &edgeTypes, nil
+}
+
+func (store *MemoryStore) LoadStats(edgeType *EdgeType) error {
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	table, err := store.table(edgeType.FullName())
+
+	if err != nil {
+		return err
+	}
+
+	stats := &EdgeTypeStats{Statuses: make(map[string]int64)}
+
+	for _, edge := range table {
+		stats.Rows++
+		stats.Statuses[edge.Status]++
+	}
+
+	edgeType.Stats = stats
+
+	return nil
+}
+
+func (store *MemoryStore) Save(edgesPtr *[]Edge) ([]EdgeResult, error) {
+	return applyOperation(store, SAVE, edgesPtr)
+}
+
+func (store *MemoryStore) Delete(edgesPtr *[]Edge) ([]EdgeResult, error) {
+	return applyOperation(store, DELETE, edgesPtr)
+}
+
+// Apply checks every edge type exists before writing, so that the
+// operations are written as a whole.
+func (store *MemoryStore) Apply(requested []Operation) ([][]EdgeResult, error) {
+
+	operations, err := mirrorOperations(store, requested)
+
+	if err != nil {
+		return nil, err
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	err = checkTables(operations, func(edgeName string) (bool, error) {
+		_, ok := store.edges[edgeName]
+		return ok, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	err = checkPreconditions(operations, func(edgeName string, ids []string) (map[string]*Edge, error) {
+
+		edges := make(map[string]*Edge, len(ids))
+
+		for _, id := range ids {
+			if edge, ok := store.edges[edgeName][id]; ok {
+				edges[id] = edge
+			}
+		}
+
+		return edges, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]EdgeResult, len(operations))
+
+	for idx, operation := range operations {
+
+		if operation.Action == DELETE {
+			results[idx] = store.deleteEdges(operation.Edges)
+		} else {
+			results[idx] = store.saveEdges(operation.Edges)
+		}
+	}
+
+	return requestResults(requested, results), nil
+}
+
+func (store *MemoryStore) saveEdges(edgesPtr *[]Edge) []EdgeResult {
+
+	outcomes := make(map[edgeKey]string, len(*edgesPtr))
+
+	for edgeName, edges := range GroupByEdgeName(edgesPtr) {
+
+		table := store.edges[edgeName]
+
+		for _, edge := range latestEdges(edges) {
+
+			current, ok := table[edge.DbId()]
+
+			won := !ok || edge.Wins(current)
+
+			outcomes[edgeKey{edgeName, edge.DbId()}] = writeOutcome(SAVE, ok, statusOf(current), won)
+
+			if won {
+				table[edge.DbId()] = copyEdge(&edge)
+			}
+		}
+	}
+
+	return edgeResults(*edgesPtr, outcomes)
+}
+
+func (store *MemoryStore) deleteEdges(edgesPtr *[]Edge) []EdgeResult {
+
+	deleted := deletedEdges(*edgesPtr)
+
+	outcomes := make(map[edgeKey]string, len(deleted))
+
+	for edgeName, edges := range GroupByEdgeName(&deleted) {
+
+		table := store.edges[edgeName]
+
+		for _, edge := range latestEdges(edges) {
+
+			key := edgeKey{edgeName, edge.DbId()}
+
+			current, ok := table[edge.DbId()]
+
+			if !ok {
+				outcomes[key] = writeOutcome(DELETE, false, "", true)
+
+				// deletes only write the ids & status, like DELETE_PART
+				tombstone := copyEdge(&edge)
+				tombstone.Score = 0
+				tombstone.Data = nil
+
+				table[edge.DbId()] = tombstone
+				continue
+			}
+
+			won := edge.Wins(current)
+
+			outcomes[key] = writeOutcome(DELETE, true, current.Status, won)
+
+			if won {
+				current.Status = edge.Status
+				current.Updated = edge.Updated
+			}
+		}
+	}
+
+	return edgeResults(deleted, outcomes)
+}
+
+func statusOf(edge *Edge) string {
+
+	if edge == nil {
+		return ""
+	}
+
+	return edge.Status
+}
+
+func (store *MemoryStore) Get(edge *Edge) (*Edge, error) {
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	table, err := store.table(*edge.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	current, ok := table[edge.DbId()]
+
+	if !ok {
+		return nil, nil
+	}
+
+	found := copyEdge(current)
+	found.Name = edge.Name
+
+	return found, nil
+}
+
+func (store *MemoryStore) List(query *EdgeQuery) (*[]Edge, string, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, "", err
+	}
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	table, err := store.table(*query.Name)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	column, direction := query.orderColumn()
+
+	var after *Edge
+
+	if query.Cursor != "" {
+		cursor, _ := DecodeCursor(query.Cursor)
+
+		if after, err = cursorPosition(column, cursor); err != nil {
+			return nil, "", &ValidationError{"cursor", "Cursor is invalid or was issued for another `order_by`"}
+		}
+	}
+
+	// the sign turns the comparison around for descending orders
+	sign := 1
+
+	if direction == "DESC" {
+		sign = -1
+	}
+
+	matches := make([]*Edge, 0)
+
+	for _, edge := range table {
+
+		if !query.matches(edge) || (column == "updated" && edge.Updated == nil) {
+			continue
+		}
+
+		if after != nil && sign*compareEdges(edge, after, column) <= 0 {
+			continue
+		}
+
+		matches = append(matches, edge)
+	}
+
+	sort.Slice(matches, func(i, j int) bool {
+		return sign*compareEdges(matches[i], matches[j], column) < 0
+	})
+
+	nextCursor := ""
+
+	if len(matches) > query.PageSize() {
+		matches = matches[:query.PageSize()]
+
+		last := matches[len(matches)-1]
+
+		cursor := Cursor{
+			OrderBy: query.OrderBy,
+			Key:     orderKey(last, column),
+			Id:      last.Id,
+		}
+
+		nextCursor = cursor.Encode()
+	}
+
+	edgeList := make([]Edge, len(matches))
+
+	for idx, edge := range matches {
+		edgeList[idx] = *copyEdge(edge)
+		edgeList[idx].Name = query.Name
+	}
+
+	return &edgeList, nextCursor, nil
+}
+
+func (store *MemoryStore) Count(query *CountQuery) (map[NodeId]int64, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	table, err := store.table(*query.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	column, nodeIds := query.nodeColumn()
+
+	counts := make(map[NodeId]int64, len(nodeIds))
+
+	for _, nodeId := range nodeIds {
+		counts[nodeId] = 0
+	}
+
+	statuses := query.Status
+
+	if len(statuses) == 0 {
+		statuses = []string{ACTIVE}
+	}
+
+	for _, edge := range table {
+
+		nodeId := edge.SrcId
+
+		if column == "dest_id" {
+			nodeId = edge.DestId
+		}
+
+		if _, ok := counts[nodeId]; !ok || !containsString(statuses, &edge.Status) {
+			continue
+		}
+
+		if query.SrcType != nil && !equalString(edge.SrcType, query.SrcType) {
+			continue
+		}
+
+		if query.DestType != nil && !equalString(edge.DestType, query.DestType) {
+			continue
+		}
+
+		counts[nodeId]++
+	}
+
+	return counts, nil
+}
+
+// CombineSets combines the neighbor sets in memory, left to right,
+// like the INTERSECT, UNION & EXCEPT of CombineSets on Postgres.
+func (store *MemoryStore) CombineSets(query *SetQuery) (*SetResult, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := canonicalSets(query, store.lookupEdge); err != nil {
+		return nil, err
+	}
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	setEdges := make([][]*Edge, len(query.Sets))
+	combined := make(map[NodeId]bool)
+
+	for idx := range query.Sets {
+
+		edges, err := store.neighborEdges(&query.Sets[idx])
+
+		if err != nil {
+			return nil, err
+		}
+
+		setEdges[idx] = edges
+
+		neighbors := make(map[NodeId]bool, len(edges))
+
+		for _, edge := range edges {
+			neighbors[query.Sets[idx].neighborOf(edge)] = true
+		}
+
+		if idx == 0 {
+			combined = neighbors
+			continue
+		}
+
+		switch query.Op {
+		case INTERSECT:
+			for nodeId := range combined {
+				if !neighbors[nodeId] {
+					delete(combined, nodeId)
+				}
+			}
+		case UNION:
+			for nodeId := range neighbors {
+				combined[nodeId] = true
+			}
+		case DIFFERENCE:
+			for nodeId := range neighbors {
+				delete(combined, nodeId)
+			}
+		}
+	}
+
+	if query.Count {
+		count := int64(len(combined))
+
+		return &SetResult{Count: &count}, nil
+	}
+
+	nodeIds := make([]NodeId, 0, len(combined))
+
+	for nodeId := range combined {
+		nodeIds = append(nodeIds, nodeId)
+	}
+
+	sort.Slice(nodeIds, func(i, j int) bool {
+		return compareNodeIds(nodeIds[i], nodeIds[j]) < 0
+	})
+
+	limit := query.Limit
+
+	if limit == 0 {
+		limit = DEFAULT_QUERY_LIMIT
+	}
+
+	if len(nodeIds) > limit {
+		nodeIds = nodeIds[:limit]
+	}
+
+	inResult := make(map[NodeId]bool, len(nodeIds))
+
+	for _, nodeId := range nodeIds {
+		inResult[nodeId] = true
+	}
+
+	result := &SetResult{NodeIds: nodeIds, Edges: make([]Edge, 0)}
+
+	for idx, edges := range setEdges {
+		for _, edge := range edges {
+
+			if !inResult[query.Sets[idx].neighborOf(edge)] {
+				continue
+			}
+
+			found := *copyEdge(edge)
+			found.Name = query.Sets[idx].Name
+
+			result.Edges = append(result.Edges, found)
+		}
+	}
+
+	return result, nil
+}
+
+// neighborEdges returns the active edges of the node of the set, by
+// id.
+func (store *MemoryStore) neighborEdges(set *NeighborSet) ([]*Edge, error) {
+
+	table, err := store.table(*set.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	edges := make([]*Edge, 0)
+
+	for _, edge := range table {
+
+		nodeId := edge.SrcId
+
+		if set.Direction == IN {
+			nodeId = edge.DestId
+		}
+
+		if nodeId == set.NodeId && edge.Status == ACTIVE {
+			edges = append(edges, edge)
+		}
+	}
+
+	sort.Slice(edges, func(i, j int) bool {
+		return edges[i].Id < edges[j].Id
+	})
+
+	return edges, nil
+}
+
+// lookupEdge is the edgeLookup of the edge types in memory, which
+// all have a table.
+func (store *MemoryStore) lookupEdge(edgeName string) (*EdgeType, bool, error) {
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	if _, ok := store.edges[edgeName]; !ok {
+		return nil, false, nil
+	}
+
+	edgeType := *store.types[edgeName]
+
+	return &edgeType, true, nil
+}
+
+// Traverse walks the hops over the edges in memory, keeping the
+// edges of every node the way Traverse does on Postgres.
+func (store *MemoryStore) Traverse(traversal *Traversal) (*[]ReachedNode, error) {
+
+	if err := traversal.Validate(); err != nil {
+		return nil, err
+	}
+
+	edgeType, err := hopTypes(traversal, store.lookupEdge)
+
+	if err != nil {
+		return nil, err
+	}
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	return walk(traversal, edgeType, store.hopEdges)
+}
+
+// hopEdges ranks the edges of every node of the frontier like
+// HOP_PART, by score, highest first, & then by id.
+func (store *MemoryStore) hopEdges(hop *Hop, frontier []NodeId) ([]hopRow, error) {
+
+	table, err := store.table(*hop.Name)
+
+	if err != nil {
+		return nil, err
+	}
+
+	inFrontier := make(map[NodeId]bool, len(frontier))
+
+	for _, nodeId := range frontier {
+		inFrontier[nodeId] = true
+	}
+
+	statuses := hop.Status
+
+	if len(statuses) == 0 {
+		statuses = []string{ACTIVE}
+	}
+
+	rows := make([]hopRow, 0)
+	edges := make([]*Edge, 0)
+
+	for _, edge := range table {
+
+		row := hopRow{NodeId: edge.SrcId, NeighborId: edge.DestId, NeighborType: edge.DestType}
+
+		if hop.Direction == IN {
+			row = hopRow{NodeId: edge.DestId, NeighborId: edge.SrcId, NeighborType: edge.SrcType}
+		}
+
+		if !inFrontier[row.NodeId] || !containsString(statuses, &edge.Status) {
+			continue
+		}
+
+		if hop.SrcType != nil && !equalString(edge.SrcType, hop.SrcType) {
+			continue
+		}
+
+		if hop.DestType != nil && !equalString(edge.DestType, hop.DestType) {
+			continue
+		}
+
+		rows = append(rows, row)
+		edges = append(edges, edge)
+	}
+
+	order := make([]int, len(rows))
+
+	for idx := range order {
+		order[idx] = idx
+	}
+
+	sort.Slice(order, func(i, j int) bool {
+
+		a, b := order[i], order[j]
+
+		if byNode := compareNodeIds(rows[a].NodeId, rows[b].NodeId); byNode != 0 {
+			return byNode < 0
+		}
+
+		if edges[a].Score != edges[b].Score {
+			return edges[a].Score > edges[b].Score
+		}
+
+		return edges[a].Id < edges[b].Id
+	})
+
+	ranked := make([]hopRow, 0, len(rows))
+	ranks := make(map[NodeId]int)
+
+	for _, idx := range order {
+
+		if ranks[rows[idx].NodeId] < hop.fanOut() {
+			ranks[rows[idx].NodeId]++
+			ranked = append(ranked, rows[idx])
+		}
+	}
+
+	return ranked, nil
+}
+
+// CreateKey mints the key and keeps it, with only the hash of its
+// secret.
+func (store *MemoryStore) CreateKey(key *ApiKey) (string, error) {
+
+	token, err := MintKey(key)
+
+	if err != nil {
+		return "", err
+	}
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	created := time.Now()
+	key.Created = &created
+
+	stored := *key
+	store.keys[key.Id] = &stored
+
+	return token, nil
+}
+
+func (store *MemoryStore) GetKey(id string) (*ApiKey, error) {
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	key, ok := store.keys[id]
+
+	if !ok {
+		return nil, nil
+	}
+
+	found := *key
+
+	return &found, nil
+}
+
+func (store *MemoryStore) ListKeys() (*[]ApiKey, error) {
+
+	store.lock.RLock()
+	defer store.lock.RUnlock()
+
+	keys := make([]ApiKey, 0, len(store.keys))
+
+	for _, key := range store.keys {
+		keys = append(keys, *key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		if !keys[i].Created.Equal(*keys[j].Created) {
+			return keys[i].Created.Before(*keys[j].Created)
+		}
+
+		return keys[i].Id < keys[j].Id
+	})
+
+	return &keys, nil
+}
+
+func (store *MemoryStore) RevokeKey(id string) (bool, error) {
+
+	store.lock.Lock()
+	defer store.lock.Unlock()
+
+	key, ok := store.keys[id]
+
+	if !ok || key.Revoked != nil {
+		return false, nil
+	}
+
+	revoked := time.Now()
+	key.Revoked = &revoked
+
+	return true, nil
+}
+
+// table returns the edges of a type, failing like a query on a
+// missing Postgres table when the type was never created.
+func (store *MemoryStore) table(edgeName string) (map[string]*Edge, error) {
+
+	table, ok := store.edges[edgeName]
+
+	if !ok {
+		return nil, &UnknownEdgeError{edgeName}
+	}
+
+	return table, nil
+}
+
+func (query *EdgeQuery) matches(edge *Edge) bool {
+
+	if len(query.SrcIds) > 0 && !containsId(query.SrcIds, edge.SrcId) {
+		return false
+	}
+
+	if len(query.DestIds) > 0 && !containsId(query.DestIds, edge.DestId) {
+		return false
+	}
+
+	if query.SrcType != nil && !equalString(edge.SrcType, query.SrcType) {
+		return false
+	}
+
+	if query.DestType != nil && !equalString(edge.DestType, query.DestType) {
+		return false
+	}
+
+	if len(query.Qualifiers) > 0 && (edge.Qualifier == nil || !containsString(query.Qualifiers, edge.Qualifier)) {
+		return false
+	}
+
+	statuses := query.Status
+
+	if len(statuses) == 0 {
+		statuses = []string{ACTIVE}
+	}
+
+	if !containsString(statuses, &edge.Status) {
+		return false
+	}
+
+	if query.Score != nil && query.Score.Min != nil && float64(edge.Score) < *query.Score.Min {
+		return false
+	}
+
+	if query.Score != nil && query.Score.Max != nil && float64(edge.Score) > *query.Score.Max {
+		return false
+	}
+
+	if query.Updated != nil && query.Updated.From != nil && (edge.Updated == nil || edge.Updated.Before(*query.Updated.From)) {
+		return false
+	}
+
+	if query.Updated != nil && query.Updated.To != nil && (edge.Updated == nil || !edge.Updated.Before(*query.Updated.To)) {
+		return false
+	}
+
+	return true
+}
+
+// compareEdges orders edges by the column and then by id, the way
+// the ORDER BY of an edge query does.
+func compareEdges(a *Edge, b *Edge, column string) int {
+
+	switch column {
+	case "score":
+		if a.Score != b.Score {
+			if a.Score < b.Score {
+				return -1
+			}
+			return 1
+		}
+	case "updated":
+		if !a.Updated.Equal(*b.Updated) {
+			if a.Updated.Before(*b.Updated) {
+				return -1
+			}
+			return 1
+		}
+	}
+
+	return strings.Compare(a.Id, b.Id)
+}
+
+func orderKey(edge *Edge, column string) string {
+
+	switch column {
+	case "score":
+		return strconv.FormatFloat(float64(edge.Score), 'g', -1, 32)
+	case "updated":
+		return edge.Updated.Format(time.RFC3339Nano)
+	}
+
+	return edge.Id
+}
+
+// cursorPosition is an edge at the position marked by the cursor.
+func cursorPosition(column string, cursor *Cursor) (*Edge, error) {
+
+	position := &Edge{Id: cursor.Id}
+
+	switch column {
+	case "score":
+		score, err := strconv.ParseFloat(cursor.Key, 32)
+
+		if err != nil {
+			return nil, err
+		}
+
+		position.Score = float32(score)
+	case "updated":
+		updated, err := time.Parse(time.RFC3339Nano, cursor.Key)
+
+		if err != nil {
+			return nil, err
+		}
+
+		position.Updated = &updated
+	}
+
+	return position, nil
+}
+
+func copyEdge(edge *Edge) *Edge {
+
+	copied := *edge
+	copied.Id = edge.DbId()
+	copied.If = nil
+
+	if edge.Data != nil {
+		data := make(Data, len(*edge.Data))
+
+		for key, value := range *edge.Data {
+			data[key] = value
+		}
+
+		copied.Data = &data
+	}
+
+	return &copied
+}
+
+// compareNodeIds orders integer ids by value, like the bigint
+// columns of Postgres, and other ids as strings.
+func compareNodeIds(a NodeId, b NodeId) int {
+
+	if a.IsInt() && b.IsInt() {
+
+		x, _ := strconv.ParseInt(string(a), 10, 64)
+		y, _ := strconv.ParseInt(string(b), 10, 64)
+
+		if x != y {
+			if x < y {
+				return -1
+			}
+			return 1
+		}
+
+		return 0
+	}
+
+	return strings.Compare(string(a), string(b))
+}
+
+func containsId(ids []NodeId, id NodeId) bool {
+
+	for _, item := range ids {
+		if item == id {
+			return true
+		}
+	}
+
+	return false
+}
+
+func equalString(a *string, b *string) bool {
+	return a != nil && b != nil && *a == *b
+}
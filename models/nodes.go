@@ -0,0 +1,136 @@
+package models
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const (
+	INT_ID    string = "int"
+	STRING_ID string = "string"
+	UUID_ID   string = "uuid"
+)
+
+// Column types of the node ids of every id type, the ids of edge
+// types registered without an `id_type` are integers.
+var ID_COLUMN_TYPES = map[string]string{
+	"":        "bigint",
+	INT_ID:    "bigint",
+	STRING_ID: "varchar",
+	UUID_ID:   "uuid",
+}
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}$`)
+
+// NodeId is the id of a node, a Datastore integer id, a key name or
+// a UUID. Ids are read from JSON numbers or strings, and integer
+// ids are written back as numbers.
+type NodeId string
+
+func (id NodeId) MarshalJSON() ([]byte, error) {
+
+	if id.IsInt() {
+		return []byte(id), nil
+	}
+
+	return json.Marshal(string(id))
+}
+
+func (id *NodeId) UnmarshalJSON(data []byte) error {
+
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+
+	var value interface{}
+
+	if err := decoder.Decode(&value); err != nil {
+		return err
+	}
+
+	switch value := value.(type) {
+	case nil:
+		*id = ""
+	case json.Number:
+		*id = NodeId(value.String())
+	case string:
+		*id = NodeId(value)
+	default:
+		return fmt.Errorf("node id must be a number or a string, got %s", data)
+	}
+
+	return nil
+}
+
+// Scan reads ids from integer & text columns.
+func (id *NodeId) Scan(src interface{}) error {
+
+	switch src := src.(type) {
+	case nil:
+		*id = ""
+	case int64:
+		*id = NodeId(strconv.FormatInt(src, 10))
+	case []byte:
+		*id = NodeId(src)
+	case string:
+		*id = NodeId(src)
+	default:
+		return fmt.Errorf("cannot read node id from %T", src)
+	}
+
+	return nil
+}
+
+// IsInt tells if the id is an integer, written the way Go formats it.
+func (id NodeId) IsInt() bool {
+
+	value, err := strconv.ParseInt(string(id), 10, 64)
+
+	return err == nil && strconv.FormatInt(value, 10) == string(id)
+}
+
+// Canonical is the id in the form the column of the id type returns
+// it, UUIDs in lowercase & integers without leading zeros or `+`.
+// Ids are compared in this form, in edge ids & results keyed by id.
+func (id NodeId) Canonical(idType string) NodeId {
+
+	switch idType {
+	case STRING_ID:
+		return id
+	case UUID_ID:
+		return NodeId(strings.ToLower(string(id)))
+	default:
+		value, err := strconv.ParseInt(string(id), 10, 64)
+
+		if err != nil {
+			return id
+		}
+
+		return NodeId(strconv.FormatInt(value, 10))
+	}
+}
+
+// CanonicalIds puts the ids in their Canonical form, in place.
+func CanonicalIds(ids []NodeId, idType string) {
+
+	for idx := range ids {
+		ids[idx] = ids[idx].Canonical(idType)
+	}
+}
+
+// Valid tells if the id can be saved in an edge type of the id type,
+// string ids cannot contain the ID_SEPARATOR of edge ids.
+func (id NodeId) Valid(idType string) bool {
+
+	switch idType {
+	case STRING_ID:
+		return id != "" && !strings.Contains(string(id), ID_SEPARATOR)
+	case UUID_ID:
+		return uuidPattern.MatchString(string(id))
+	default:
+		return id.IsInt() && id != "0"
+	}
+}
@@ -0,0 +1,189 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	GET_PART string = " WHERE id = $1"
+)
+
+// PostgresStore keeps every edge type in its own table, created by
+// database.CreateTable.
+type PostgresStore struct {
+	Db    *sqlx.DB
+	stats *statsCache
+}
+
+var (
+	_ EdgeStore    = (*PostgresStore)(nil)
+	_ SetCombiner  = (*PostgresStore)(nil)
+	_ Traverser    = (*PostgresStore)(nil)
+	_ FeedStore    = (*PostgresStore)(nil)
+	_ SQLRunner    = (*PostgresStore)(nil)
+	_ KeyStore     = (*PostgresStore)(nil)
+	_ CounterStore = (*PostgresStore)(nil)
+)
+
+func NewPostgresStore(db *sqlx.DB) *PostgresStore {
+	return &PostgresStore{Db: db, stats: newStatsCache()}
+}
+
+func (store *PostgresStore) Close() error {
+	return store.Db.Close()
+}
+
+func (store *PostgresStore) CreateType(edgeType *EdgeType) error {
+
+	edgeName := edgeType.FullName()
+
+	if err := database.CreateTable(store.Db, edgeName, edgeType.Options.IdColumnType()); err != nil {
+		return err
+	}
+
+	if err := database.CreateDefaultIndexes(store.Db, edgeName); err != nil {
+		return err
+	}
+
+	if edgeType.Options.Counters {
+
+		if err := database.CreateCountsTable(store.Db, edgeName, edgeType.Options.IdColumnType()); err != nil {
+			return err
+		}
+
+		if err := RebuildCounts(store.Db, edgeName); err != nil {
+			return err
+		}
+	}
+
+	return RegisterEdgeType(store.Db, edgeType)
+}
+
+func (store *PostgresStore) GetType(edgeName string) (*EdgeType, error) {
+	return GetEdgeType(store.Db, edgeName)
+}
+
+func (store *PostgresStore) ListTypes() (*[]EdgeType, error) {
+	return ListEdgeTypes(store.Db)
+}
+
+func (store *PostgresStore) LoadStats(edgeType *EdgeType) error {
+
+	edgeName := edgeType.FullName()
+
+	stats, err := store.stats.load(edgeName, func() (*EdgeTypeStats, error) {
+		return countStats(store.Db, database.QuoteName(edgeName))
+	})
+
+	if err != nil {
+		return err
+	}
+
+	edgeType.Stats = stats
+
+	return nil
+}
+
+func (store *PostgresStore) Save(edgesPtr *[]Edge) ([]EdgeResult, error) {
+	return applyOperation(store, SAVE, edgesPtr)
+}
+
+func (store *PostgresStore) Delete(edgesPtr *[]Edge) ([]EdgeResult, error) {
+	return applyOperation(store, DELETE, edgesPtr)
+}
+
+func (store *PostgresStore) Apply(requested []Operation) ([][]EdgeResult, error) {
+
+	operations, err := mirrorOperations(store, requested)
+
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := ApplyMany(store.Db, operations)
+
+	if err != nil {
+		return nil, err
+	}
+
+	store.stats.forget(operations)
+
+	return requestResults(requested, results), nil
+}
+
+func (store *PostgresStore) Get(edge *Edge) (*Edge, error) {
+
+	edgeName := *edge.Name
+
+	var row cursorEdge
+
+	err := store.Db.Get(&row, fmt.Sprintf(SELECT_PART, database.QuoteName(edgeName), "id")+GET_PART, edge.DbId())
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	row.Edge.Name = &edgeName
+
+	return &row.Edge, nil
+}
+
+func (store *PostgresStore) List(query *EdgeQuery) (*[]Edge, string, error) {
+	return FindEdges(store.Db, query)
+}
+
+func (store *PostgresStore) Count(query *CountQuery) (map[NodeId]int64, error) {
+	return CountEdges(store.Db, query)
+}
+
+func (store *PostgresStore) RebuildCounts(edgeName string) error {
+	return RebuildCounts(store.Db, edgeName)
+}
+
+func (store *PostgresStore) CombineSets(query *SetQuery) (*SetResult, error) {
+	return CombineSets(store.Db, query)
+}
+
+func (store *PostgresStore) Traverse(traversal *Traversal) (*[]ReachedNode, error) {
+	return Traverse(store.Db, traversal)
+}
+
+func (store *PostgresStore) CreateFeed(feed *Feed) error {
+	return CreateFeed(store.Db, feed)
+}
+
+func (store *PostgresStore) GetFeed(feedName string) (*Feed, error) {
+	return GetFeed(store.Db, feedName)
+}
+
+func (store *PostgresStore) ReadFeed(feed *Feed, userId int64, limit int, cursor string) (*[]Edge, string, error) {
+	return ReadFeed(store.Db, feed, userId, limit, cursor)
+}
+
+func (store *PostgresStore) RunQuery(query string) (*[]Edge, error) {
+	return RunQuery(store.Db, query)
+}
+
+func (store *PostgresStore) CreateKey(key *ApiKey) (string, error) {
+	return CreateKey(store.Db, key)
+}
+
+func (store *PostgresStore) GetKey(id string) (*ApiKey, error) {
+	return GetKey(store.Db, id)
+}
+
+func (store *PostgresStore) ListKeys() (*[]ApiKey, error) {
+	return ListKeys(store.Db)
+}
+
+func (store *PostgresStore) RevokeKey(id string) (bool, error) {
+	return RevokeKey(store.Db, id)
+}
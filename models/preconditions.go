@@ -0,0 +1,203 @@
+package models
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	// Serializes the writes of an edge, conditional or not, including
+	// the first write of an edge that is not stored yet.
+	EDGE_LOCK_PART string = `
+		SELECT pg_advisory_xact_lock(hashtext(key)) FROM unnest($1::text[]) AS key
+	`
+)
+
+// Precondition is the state the stored edge must be in for a save
+// or delete of the edge to be written. `exists` is about an active
+// edge, deleted edges do not exist. Every condition that is given
+// has to hold.
+type Precondition struct {
+	Exists  *bool      `json:"exists,omitempty"`
+	Status  *string    `json:"status,omitempty"`
+	Updated *time.Time `json:"updated,omitempty"`
+}
+
+// Failed lists the conditions the stored edge does not meet, which
+// is nil when there is no stored edge.
+func (precondition *Precondition) Failed(current *Edge) []string {
+
+	failed := make([]string, 0)
+
+	exists := current != nil && current.Status == ACTIVE
+
+	if precondition.Exists != nil && *precondition.Exists != exists {
+		failed = append(failed, "exists")
+	}
+
+	if precondition.Status != nil && (current == nil || current.Status != *precondition.Status) {
+		failed = append(failed, "status")
+	}
+
+	if precondition.Updated != nil && (current == nil || current.Updated == nil || !current.Updated.Equal(*precondition.Updated)) {
+		failed = append(failed, "updated")
+	}
+
+	return failed
+}
+
+// FailedPrecondition points at an edge of a write whose
+// preconditions failed, with the conditions that failed.
+type FailedPrecondition struct {
+	Field  string   `json:"field"`
+	Id     string   `json:"id"`
+	Failed []string `json:"failed"`
+}
+
+// PreconditionError is returned by stores that did not write any
+// edge of a write, because the preconditions of some edges failed.
+type PreconditionError struct {
+	Edges []FailedPrecondition
+}
+
+func (err *PreconditionError) Error() string {
+	return fmt.Sprintf("Preconditions of %d edges failed, no edges were written", len(err.Edges))
+}
+
+// currentEdges looks up the stored edges of an edge type by their id.
+type currentEdges func(edgeName string, ids []string) (map[string]*Edge, error)
+
+// checkPreconditions checks the preconditions of every edge of the
+// operations against the stored edges, before any of them is
+// written.
+func checkPreconditions(operations []Operation, lookup currentEdges) error {
+
+	conditionalIds := make(map[string][]string)
+
+	for _, operation := range operations {
+		for _, edge := range *operation.Edges {
+			if edge.If != nil {
+				conditionalIds[*edge.Name] = append(conditionalIds[*edge.Name], edge.DbId())
+			}
+		}
+	}
+
+	if len(conditionalIds) == 0 {
+		return nil
+	}
+
+	stored := make(map[string]map[string]*Edge, len(conditionalIds))
+
+	for edgeName, ids := range conditionalIds {
+
+		edges, err := lookup(edgeName, ids)
+
+		if err != nil {
+			return err
+		}
+
+		stored[edgeName] = edges
+	}
+
+	failedEdges := make([]FailedPrecondition, 0)
+
+	for opIdx, operation := range operations {
+		for idx, edge := range *operation.Edges {
+
+			if edge.If == nil {
+				continue
+			}
+
+			if failed := edge.If.Failed(stored[*edge.Name][edge.DbId()]); len(failed) > 0 {
+				failedEdges = append(failedEdges, FailedPrecondition{
+					Field:  fmt.Sprintf("operations.%d.edges.%d", opIdx, idx),
+					Id:     edge.DbId(),
+					Failed: failed,
+				})
+			}
+		}
+	}
+
+	if len(failedEdges) > 0 {
+		return &PreconditionError{failedEdges}
+	}
+
+	return nil
+}
+
+// applyOperation writes a save or a delete, pointing the failed
+// preconditions at the edges of the request.
+func applyOperation(store EdgeStore, action string, edgesPtr *[]Edge) ([]EdgeResult, error) {
+
+	results, err := store.Apply([]Operation{{action, edgesPtr}})
+
+	if preconditionErr, ok := err.(*PreconditionError); ok {
+		for idx := range preconditionErr.Edges {
+			preconditionErr.Edges[idx].Field = strings.TrimPrefix(preconditionErr.Edges[idx].Field, "operations.0.")
+		}
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return results[0], nil
+}
+
+// lockEdges locks the ids of every edge of the operations in the
+// transaction, so the stored edges checked by the preconditions do
+// not change until the writes are committed.
+func lockEdges(tx *sqlx.Tx, operations []Operation) error {
+
+	locked := make(map[string]bool)
+	keys := make([]string, 0)
+
+	for _, operation := range operations {
+		for _, edge := range *operation.Edges {
+
+			key := *edge.Name + ID_SEPARATOR + edge.DbId()
+
+			if !locked[key] {
+				locked[key] = true
+				keys = append(keys, key)
+			}
+		}
+	}
+
+	// locks are taken in order, so writes do not deadlock
+	sort.Strings(keys)
+
+	_, err := tx.Exec(EDGE_LOCK_PART, pq.Array(keys))
+
+	return err
+}
+
+// storedEdges looks up the stored edges in the transaction, which
+// holds the locks of lockEdges.
+func storedEdges(tx *sqlx.Tx) currentEdges {
+
+	return func(edgeName string, ids []string) (map[string]*Edge, error) {
+
+		rows := make([]cursorEdge, 0)
+
+		err := tx.Select(&rows, fmt.Sprintf(SELECT_PART, database.QuoteName(edgeName), "id")+" WHERE id = ANY($1)", pq.Array(ids))
+
+		if err != nil {
+			return nil, err
+		}
+
+		edges := make(map[string]*Edge, len(rows))
+
+		for idx := range rows {
+			edges[rows[idx].Id] = &rows[idx].Edge
+		}
+
+		return edges, nil
+	}
+}
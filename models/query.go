@@ -0,0 +1,319 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	DEFAULT_QUERY_LIMIT int = 100
+	MAX_QUERY_LIMIT     int = 1000
+
+	SELECT_PART string = `
+		SELECT
+		  id,
+		  src_id,
+		  src_type,
+		  dest_id,
+		  dest_type,
+		  qualifier,
+		  score,
+		  data,
+		  status,
+		  updated,
+		  %[2]s::text AS cursor_key
+		FROM %[1]s
+	`
+)
+
+// Columns an edge query can be ordered by, with the type a cursor
+// key is cast back to. A `-` prefix on `order_by` sorts in
+// descending order. Ties are always broken by `id`.
+var ORDER_COLUMNS = map[string]string{
+	"id":      "varchar",
+	"score":   "decimal",
+	"updated": "timestamp",
+}
+
+// EdgeQuery is the JSON query model accepted by `/v1/edges/query`.
+// Every filter is optional except the edge name; list filters match
+// any of the given values and ranges are inclusive of `min`/`from`
+// and exclusive of `to`.
+//
+// Results are paged with `cursor`, the opaque value returned along
+// with the previous page. Edges without a value for the `order_by`
+// column are left out of ordered listings.
+type EdgeQuery struct {
+	Name       *string     `json:"name"`
+	SrcIds     []NodeId    `json:"src_id"`
+	DestIds    []NodeId    `json:"dest_id"`
+	SrcType    *string     `json:"src_type"`
+	DestType   *string     `json:"dest_type"`
+	Qualifiers []string    `json:"qualifier"`
+	Status     []string    `json:"status"`
+	Score      *ScoreRange `json:"score"`
+	Updated    *TimeRange  `json:"updated"`
+	OrderBy    string      `json:"order_by"`
+	Limit      int         `json:"limit"`
+	Cursor     string      `json:"cursor"`
+}
+
+type ScoreRange struct {
+	Min *float64 `json:"min"`
+	Max *float64 `json:"max"`
+}
+
+type TimeRange struct {
+	From *time.Time `json:"from"`
+	To   *time.Time `json:"to"`
+}
+
+// Cursor marks the last edge of a page by its order key & id, which
+// are unique and stable across writes to other edges.
+type Cursor struct {
+	OrderBy string `json:"o"`
+	Key     string `json:"k"`
+	Id      string `json:"i"`
+}
+
+func (cursor *Cursor) Encode() string {
+
+	cursorJson, _ := json.Marshal(cursor)
+
+	return base64.RawURLEncoding.EncodeToString(cursorJson)
+}
+
+func DecodeCursor(value string) (*Cursor, error) {
+
+	cursorJson, err := base64.RawURLEncoding.DecodeString(value)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var cursor Cursor
+
+	if err := json.Unmarshal(cursorJson, &cursor); err != nil {
+		return nil, err
+	}
+
+	return &cursor, nil
+}
+
+// ValidationError points at the request field that made a query
+// or an edge invalid.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (err *ValidationError) Error() string {
+	return err.Message
+}
+
+func (query *EdgeQuery) Validate() error {
+
+	if query.Name == nil || *query.Name == "" {
+		return &ValidationError{"name", "Query must have an edge `name`"}
+	}
+
+	if _, ok := ORDER_COLUMNS[strings.TrimPrefix(query.OrderBy, "-")]; query.OrderBy != "" && !ok {
+		return &ValidationError{"order_by", fmt.Sprintf("Cannot order edges by `%s`", query.OrderBy)}
+	}
+
+	if query.Limit < 0 || query.Limit > MAX_QUERY_LIMIT {
+		return &ValidationError{"limit", fmt.Sprintf("Limit cannot be negative or more than %d", MAX_QUERY_LIMIT)}
+	}
+
+	if query.Cursor != "" {
+		cursor, err := DecodeCursor(query.Cursor)
+
+		if err != nil || cursor.OrderBy != query.OrderBy {
+			return &ValidationError{"cursor", "Cursor is invalid or was issued for another `order_by`"}
+		}
+	}
+
+	return nil
+}
+
+func (query *EdgeQuery) orderColumn() (string, string) {
+
+	column := strings.TrimPrefix(query.OrderBy, "-")
+
+	if column == "" {
+		column = "id"
+	}
+
+	direction := "ASC"
+
+	if strings.HasPrefix(query.OrderBy, "-") {
+		direction = "DESC"
+	}
+
+	return column, direction
+}
+
+// ToSQL compiles the query into a parameterized SELECT against the
+// edge table. Only the table name and the order column are written
+// into the statement, every value is passed as an argument. The
+// statement fetches one edge more than the limit, to tell if there
+// is a next page.
+func (query *EdgeQuery) ToSQL() (string, []interface{}, error) {
+
+	if err := query.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	conditions := make([]string, 0)
+	valueArgs := make([]interface{}, 0)
+
+	addCondition := func(condition string, value interface{}) {
+		valueArgs = append(valueArgs, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(valueArgs)))
+	}
+
+	if len(query.SrcIds) > 0 {
+		addCondition("src_id = ANY($%d)", pq.Array(query.SrcIds))
+	}
+
+	if len(query.DestIds) > 0 {
+		addCondition("dest_id = ANY($%d)", pq.Array(query.DestIds))
+	}
+
+	if query.SrcType != nil {
+		addCondition("src_type = $%d", *query.SrcType)
+	}
+
+	if query.DestType != nil {
+		addCondition("dest_type = $%d", *query.DestType)
+	}
+
+	if len(query.Qualifiers) > 0 {
+		addCondition("qualifier = ANY($%d)", pq.Array(query.Qualifiers))
+	}
+
+	if len(query.Status) > 0 {
+		addCondition("status = ANY($%d)", pq.Array(query.Status))
+	} else {
+		addCondition("status = $%d", ACTIVE)
+	}
+
+	if query.Score != nil && query.Score.Min != nil {
+		addCondition("score >= $%d", *query.Score.Min)
+	}
+
+	if query.Score != nil && query.Score.Max != nil {
+		addCondition("score <= $%d", *query.Score.Max)
+	}
+
+	if query.Updated != nil && query.Updated.From != nil {
+		addCondition("updated >= $%d", *query.Updated.From)
+	}
+
+	if query.Updated != nil && query.Updated.To != nil {
+		addCondition("updated < $%d", *query.Updated.To)
+	}
+
+	column, direction := query.orderColumn()
+
+	if column != "id" {
+		conditions = append(conditions, column+" IS NOT NULL")
+	}
+
+	if query.Cursor != "" {
+		cursor, _ := DecodeCursor(query.Cursor)
+
+		comparison := ">"
+
+		if direction == "DESC" {
+			comparison = "<"
+		}
+
+		valueArgs = append(valueArgs, cursor.Key, cursor.Id)
+
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s, id) %s ($%d::%s, $%d)",
+			column, comparison, len(valueArgs)-1, ORDER_COLUMNS[column], len(valueArgs),
+		))
+	}
+
+	sql := fmt.Sprintf(SELECT_PART, database.QuoteName(*query.Name), column)
+
+	sql = sql + " WHERE " + strings.Join(conditions, " AND ")
+
+	sql = sql + fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s", column, direction)
+
+	valueArgs = append(valueArgs, query.PageSize()+1)
+	sql = sql + fmt.Sprintf(" LIMIT $%d", len(valueArgs))
+
+	return sql, valueArgs, nil
+}
+
+func (query *EdgeQuery) PageSize() int {
+
+	if query.Limit == 0 {
+		return DEFAULT_QUERY_LIMIT
+	}
+
+	return query.Limit
+}
+
+type cursorEdge struct {
+	Edge
+	CursorKey string `db:"cursor_key"`
+}
+
+// FindEdges returns a page of edges matching the query and the
+// cursor for the next page, which is empty on the last page.
+func FindEdges(db *sqlx.DB, query *EdgeQuery) (*[]Edge, string, error) {
+
+	sql, valueArgs, err := query.ToSQL()
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := findEdge(lookupEdge(db), *query.Name); err != nil {
+		return nil, "", err
+	}
+
+	rows := make([]cursorEdge, 0)
+
+	err = db.Select(&rows, sql, valueArgs...)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+
+	if len(rows) > query.PageSize() {
+		rows = rows[:query.PageSize()]
+
+		last := rows[len(rows)-1]
+
+		cursor := Cursor{
+			OrderBy: query.OrderBy,
+			Key:     last.CursorKey,
+			Id:      last.Id,
+		}
+
+		nextCursor = cursor.Encode()
+	}
+
+	edgeList := make([]Edge, len(rows))
+
+	for idx, row := range rows {
+		edgeList[idx] = row.Edge
+		edgeList[idx].Name = query.Name
+	}
+
+	return &edgeList, nextCursor, nil
+}
@@ -0,0 +1,97 @@
+package models
+
+const (
+	CREATED   string = "created"
+	UPDATED   string = "updated"
+	STALE     string = "stale"
+	NOT_FOUND string = "not_found"
+)
+
+// EdgeResult is the outcome of the write of one edge of a request.
+//
+// Saves are `created` when there was no active or inactive edge,
+// or only a deleted one, and `updated` otherwise. Deletes are
+// `deleted`, or `not_found` when there was no edge that was not
+// already deleted. Writes that lost to a later write of the edge, in
+// the store or in the same request, are `stale`.
+type EdgeResult struct {
+	Name    *string `json:"name"`
+	Id      string  `json:"id"`
+	Outcome string  `json:"outcome"`
+}
+
+type edgeKey struct {
+	Name string
+	Id   string
+}
+
+// writeOutcome is the outcome of a write that replaced the stored
+// edge or not, from the status of the stored edge, if it existed.
+func writeOutcome(action string, existed bool, status string, won bool) string {
+
+	if !won {
+		return STALE
+	}
+
+	missing := !existed || status == DELETED
+
+	if action == DELETE && missing {
+		return NOT_FOUND
+	}
+
+	if action == DELETE {
+		return DELETED
+	}
+
+	if missing {
+		return CREATED
+	}
+
+	return UPDATED
+}
+
+// edgeResults are the outcomes of the edges of a write, in order,
+// from the outcomes of the edges that were written after dropping
+// the edges that lost to a later write in the same request, see
+// latestEdges.
+func edgeResults(edges []Edge, outcomes map[edgeKey]string) []EdgeResult {
+
+	winners := make(map[edgeKey]int, len(edges))
+
+	for idx, edge := range edges {
+
+		key := edgeKey{*edge.Name, edge.DbId()}
+
+		if current, ok := winners[key]; !ok || edge.Wins(&edges[current]) {
+			winners[key] = idx
+		}
+	}
+
+	results := make([]EdgeResult, len(edges))
+
+	for idx, edge := range edges {
+
+		key := edgeKey{*edge.Name, edge.DbId()}
+
+		outcome := STALE
+
+		if winners[key] == idx {
+			outcome = outcomes[key]
+		}
+
+		results[idx] = EdgeResult{Name: edge.Name, Id: edge.DbId(), Outcome: outcome}
+	}
+
+	return results
+}
+
+// requestResults leaves out the results of the mirrored edges the
+// store added to the operations of a request.
+func requestResults(operations []Operation, results [][]EdgeResult) [][]EdgeResult {
+
+	for idx, operation := range operations {
+		results[idx] = results[idx][:len(*operation.Edges)]
+	}
+
+	return results
+}
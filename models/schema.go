@@ -0,0 +1,131 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// SchemaError lists the paths of every `data` field, across all the
+// edges of a write, that does not conform to the JSON Schema of its
+// edge type.
+type SchemaError struct {
+	Message string
+	Fields  []string
+}
+
+func (err *SchemaError) Error() string {
+	return err.Message
+}
+
+// IsValidationError tells if the error is from invalid input, as
+// opposed to an error from the database.
+func IsValidationError(err error) bool {
+
+	switch err.(type) {
+	case *ValidationError, *SchemaError:
+		return true
+	}
+
+	return false
+}
+
+type compiledSchema struct {
+	source string
+	schema *gojsonschema.Schema
+}
+
+var (
+	schemaCache     = make(map[string]compiledSchema)
+	schemaCacheLock sync.Mutex
+)
+
+func CompileSchema(schema *Data) (*gojsonschema.Schema, error) {
+
+	schemaJson, err := json.Marshal(schema)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return gojsonschema.NewSchema(gojsonschema.NewBytesLoader(schemaJson))
+}
+
+// typeSchema returns the compiled schema of an edge type, compiling
+// it again only when the registered schema has changed.
+func typeSchema(edgeType *EdgeType) (*gojsonschema.Schema, error) {
+
+	schemaJson, err := json.Marshal(edgeType.Schema)
+
+	if err != nil {
+		return nil, err
+	}
+
+	schemaCacheLock.Lock()
+	defer schemaCacheLock.Unlock()
+
+	cached, ok := schemaCache[edgeType.FullName()]
+
+	if ok && cached.source == string(schemaJson) {
+		return cached.schema, nil
+	}
+
+	schema, err := CompileSchema(edgeType.Schema)
+
+	if err != nil {
+		return nil, err
+	}
+
+	schemaCache[edgeType.FullName()] = compiledSchema{string(schemaJson), schema}
+
+	return schema, nil
+}
+
+// validateData returns the paths of the invalid fields in the data
+// of the edge at idx.
+func validateData(edgeType *EdgeType, edge *Edge, idx int) ([]string, error) {
+
+	schema, err := typeSchema(edgeType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+
+	if edge.Data != nil {
+		data = map[string]interface{}(*edge.Data)
+	}
+
+	result, err := schema.Validate(gojsonschema.NewGoLoader(data))
+
+	if err != nil {
+		return nil, err
+	}
+
+	fields := make([]string, 0, len(result.Errors()))
+
+	for _, resultErr := range result.Errors() {
+
+		path := fmt.Sprintf("edges.%d.data", idx)
+
+		if field := resultErr.Field(); field != gojsonschema.STRING_CONTEXT_ROOT {
+			path = path + "." + field
+		}
+
+		if property, ok := resultErr.Details()["property"]; ok && resultErr.Type() == "required" {
+			path = path + "." + fmt.Sprint(property)
+		}
+
+		fields = append(fields, path)
+	}
+
+	return fields, nil
+}
+
+func schemaErrorMessage(fields []string) string {
+	return fmt.Sprintf("Edge data does not match the schema of its type at: %s", strings.Join(fields, ", "))
+}
@@ -0,0 +1,280 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	INTERSECT  string = "intersect"
+	UNION      string = "union"
+	DIFFERENCE string = "difference"
+
+	NEIGHBOR_PART string = `
+		SELECT %[2]s AS node_id
+		FROM %[1]s
+		WHERE %[3]s = $%[4]d AND status = '%[5]s'
+	`
+
+	SET_COUNT_PART string = `
+		SELECT count(*) FROM (%s) AS nodes
+	`
+
+	SET_NODES_PART string = `
+		%s ORDER BY node_id LIMIT $%d
+	`
+
+	SET_EDGES_PART string = `
+		SELECT
+		  id,
+		  $%[5]d::varchar AS name,
+		  src_id,
+		  src_type,
+		  dest_id,
+		  dest_type,
+		  qualifier,
+		  score,
+		  data,
+		  status,
+		  updated
+		FROM %[1]s
+		WHERE %[3]s = $%[4]d AND status = '%[7]s'
+		  AND %[2]s = ANY($%[6]d)
+	`
+)
+
+var SET_OPERATORS = map[string]string{
+	INTERSECT:  "INTERSECT",
+	UNION:      "UNION",
+	DIFFERENCE: "EXCEPT",
+}
+
+// NeighborSet is the set of active neighbors of a node on one edge
+// type. `out` neighbors are the `dest_id`s of the node's edges and
+// `in` neighbors are the `src_id`s of the edges pointing to it.
+type NeighborSet struct {
+	Name      *string `json:"name"`
+	NodeId    NodeId  `json:"node_id"`
+	Direction string  `json:"direction"`
+}
+
+// SetQuery combines neighbor sets, left to right, with one of
+// intersect, union or difference. For example, the users A follows
+// who also follow B are `intersect` of A's `out` and B's `in` sets
+// on `follow`.
+type SetQuery struct {
+	Op    string        `json:"op"`
+	Sets  []NeighborSet `json:"sets"`
+	Count bool          `json:"count"`
+	Limit int           `json:"limit"`
+}
+
+// SetResult has the nodes in the combined set and the edges that
+// put them in one of the neighbor sets, or only their count.
+type SetResult struct {
+	Count   *int64   `json:"count,omitempty"`
+	NodeIds []NodeId `json:"node_ids,omitempty"`
+	Edges   []Edge   `json:"edges,omitempty"`
+}
+
+func (query *SetQuery) Validate() error {
+
+	if _, ok := SET_OPERATORS[query.Op]; !ok {
+		return &ValidationError{"op", "Op must be one of intersect, union or difference"}
+	}
+
+	if len(query.Sets) < 2 {
+		return &ValidationError{"sets", "There have to be atleast two sets to combine"}
+	}
+
+	for idx, set := range query.Sets {
+
+		if set.Name == nil || *set.Name == "" {
+			return &ValidationError{fmt.Sprintf("sets.%d.name", idx), fmt.Sprintf("Set at %d does not have `name`", idx)}
+		}
+
+		if set.NodeId == "" {
+			return &ValidationError{fmt.Sprintf("sets.%d.node_id", idx), fmt.Sprintf("Set at %d does not have `node_id`", idx)}
+		}
+
+		if set.Direction != OUT && set.Direction != IN {
+			return &ValidationError{fmt.Sprintf("sets.%d.direction", idx), "Direction must be `out` or `in`"}
+		}
+	}
+
+	if query.Limit < 0 || query.Limit > MAX_QUERY_LIMIT {
+		return &ValidationError{"limit", fmt.Sprintf("Limit cannot be negative or more than %d", MAX_QUERY_LIMIT)}
+	}
+
+	return nil
+}
+
+// columns returns the neighbor column & the column matching the node.
+func (set *NeighborSet) columns() (string, string) {
+
+	if set.Direction == IN {
+		return "src_id", "dest_id"
+	}
+
+	return "dest_id", "src_id"
+}
+
+// neighborOf is the neighbor an edge of the set leads to.
+func (set *NeighborSet) neighborOf(edge *Edge) NodeId {
+
+	if set.Direction == IN {
+		return edge.SrcId
+	}
+
+	return edge.DestId
+}
+
+func (query *SetQuery) ToSQL() (string, []interface{}, error) {
+
+	if err := query.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	parts := make([]string, len(query.Sets))
+	valueArgs := make([]interface{}, 0, len(query.Sets)+1)
+
+	for idx, set := range query.Sets {
+
+		neighborColumn, nodeColumn := set.columns()
+
+		valueArgs = append(valueArgs, set.NodeId)
+
+		parts[idx] = "(" + fmt.Sprintf(NEIGHBOR_PART, database.QuoteName(*set.Name), neighborColumn, nodeColumn, len(valueArgs), ACTIVE) + ")"
+	}
+
+	sql := strings.Join(parts, " "+SET_OPERATORS[query.Op]+" ")
+
+	if query.Count {
+		return fmt.Sprintf(SET_COUNT_PART, sql), valueArgs, nil
+	}
+
+	limit := query.Limit
+
+	if limit == 0 {
+		limit = DEFAULT_QUERY_LIMIT
+	}
+
+	valueArgs = append(valueArgs, limit)
+
+	return fmt.Sprintf(SET_NODES_PART, sql, len(valueArgs)), valueArgs, nil
+}
+
+// canonicalSets puts the node id of every set in the Canonical form
+// of the id type of its edge, the one its rows are compared in, and
+// checks the id against it. Sets of edges without tables are unknown,
+// and sets of edges with other id types cannot be combined.
+func canonicalSets(query *SetQuery, lookup edgeLookup) error {
+
+	idColumnType := ""
+
+	for idx := range query.Sets {
+
+		set := &query.Sets[idx]
+
+		edgeType, exists, err := lookup(*set.Name)
+
+		if err != nil {
+			return err
+		}
+
+		if !exists {
+			return &UnknownEdgeError{*set.Name}
+		}
+
+		options := EdgeTypeOptions{}
+
+		if edgeType != nil {
+			options = edgeType.Options
+		}
+
+		set.NodeId = set.NodeId.Canonical(options.IdType)
+
+		if !set.NodeId.Valid(options.IdType) {
+			return &ValidationError{
+				fmt.Sprintf("sets.%d.node_id", idx),
+				fmt.Sprintf("Set at %d must have a `node_id` of %s", idx, options.IdColumnType()),
+			}
+		}
+
+		if idColumnType != "" && options.IdColumnType() != idColumnType {
+			return &ValidationError{
+				fmt.Sprintf("sets.%d.name", idx),
+				fmt.Sprintf("Set at %d has %s ids, the sets before it have %s ids", idx, options.IdColumnType(), idColumnType),
+			}
+		}
+
+		idColumnType = options.IdColumnType()
+	}
+
+	return nil
+}
+
+func CombineSets(db *sqlx.DB, query *SetQuery) (*SetResult, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := canonicalSets(query, lookupEdge(db)); err != nil {
+		return nil, err
+	}
+
+	sql, valueArgs, err := query.ToSQL()
+
+	if err != nil {
+		return nil, err
+	}
+
+	if query.Count {
+		var count int64
+
+		if err := db.Get(&count, sql, valueArgs...); err != nil {
+			return nil, err
+		}
+
+		return &SetResult{Count: &count}, nil
+	}
+
+	nodeIds := make([]NodeId, 0)
+
+	if err := db.Select(&nodeIds, sql, valueArgs...); err != nil {
+		return nil, err
+	}
+
+	result := &SetResult{NodeIds: nodeIds, Edges: make([]Edge, 0)}
+
+	if len(nodeIds) == 0 {
+		return result, nil
+	}
+
+	parts := make([]string, len(query.Sets))
+	edgeArgs := make([]interface{}, 0, len(query.Sets)*2+1)
+
+	edgeArgs = append(edgeArgs, pq.Array(nodeIds))
+
+	for idx, set := range query.Sets {
+
+		neighborColumn, nodeColumn := set.columns()
+
+		edgeArgs = append(edgeArgs, set.NodeId, *set.Name)
+
+		parts[idx] = fmt.Sprintf(SET_EDGES_PART, database.QuoteName(*set.Name), neighborColumn, nodeColumn, len(edgeArgs)-1, len(edgeArgs), 1, ACTIVE)
+	}
+
+	err = db.Select(&result.Edges, strings.Join(parts, " UNION ALL "), edgeArgs...)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return result, nil
+}
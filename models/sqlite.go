@@ -0,0 +1,715 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	// Times are saved as UTC text of a fixed width, so they sort &
+	// compare in order.
+	SQLITE_TIME_FORMAT string = "2006-01-02 15:04:05.000000000"
+
+	SQLITE_CREATE_SYSTEM_TABLES string = `
+	  CREATE TABLE IF NOT EXISTS loki_edge_types (
+	    namespace text NOT NULL DEFAULT '',
+	    name text NOT NULL,
+	    src_types text,
+	    dest_types text,
+	    schema text,
+	    options text,
+	    created timestamp DEFAULT CURRENT_TIMESTAMP,
+	    PRIMARY KEY (namespace, name)
+	  );
+	`
+
+	SQLITE_CREATE_EDGE_TABLE string = `
+	  CREATE TABLE IF NOT EXISTS %[1]s (
+	    id text PRIMARY KEY,
+	    src_id %[2]s,
+	    src_type text,
+	    dest_id %[2]s,
+	    dest_type text,
+	    qualifier text,
+	    score real,
+	    data text,
+	    status text,
+	    updated timestamp
+	  );
+	`
+
+	// Edge tables of the registered types created before edges had a
+	// `qualifier`, see MigrateEdgeTables.
+	SQLITE_SELECT_UNQUALIFIED_TABLES string = `
+	  SELECT t.name FROM (
+	    SELECT CASE WHEN namespace = '' THEN name ELSE namespace || '.' || name END AS name
+	    FROM loki_edge_types
+	  ) t
+	  WHERE EXISTS (SELECT 1 FROM sqlite_master m WHERE m.type = 'table' AND m.name = t.name)
+	    AND NOT EXISTS (SELECT 1 FROM pragma_table_info(t.name) c WHERE c.name = 'qualifier')
+	`
+
+	SQLITE_ADD_QUALIFIER string = "ALTER TABLE %s ADD COLUMN qualifier text"
+
+	SQLITE_TYPE_SAVE_PART string = `
+		INSERT INTO loki_edge_types (namespace, name, src_types, dest_types, schema, options)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (namespace, name) DO UPDATE
+			SET
+				src_types = excluded.src_types,
+				dest_types = excluded.dest_types,
+				schema = excluded.schema,
+				options = excluded.options
+	`
+
+	// Writes resolve conflicts like UPDATE_PART & DELETE_PART do in
+	// Postgres, with WINS_PART.
+	SQLITE_SAVE_PART string = `
+		INSERT INTO %[1]s (id, src_id, src_type, dest_id, dest_type, qualifier, score, data, status, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE
+			SET
+				src_type = excluded.src_type,
+				dest_type = excluded.dest_type,
+				score = excluded.score,
+				data = excluded.data,
+				status = excluded.status,
+				updated = excluded.updated
+			WHERE %[2]s
+	`
+
+	SQLITE_DELETE_PART string = `
+		INSERT INTO %[1]s (id, src_id, src_type, dest_id, dest_type, qualifier, status, updated)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+		ON CONFLICT (id) DO UPDATE
+			SET
+				status = excluded.status,
+				updated = excluded.updated
+			WHERE %[2]s
+	`
+
+	SQLITE_SELECT_PART string = `
+		SELECT id, src_id, src_type, dest_id, dest_type, qualifier, score, data, status, updated,
+		  CAST(%[2]s AS text) AS cursor_key
+		FROM %[1]s
+	`
+
+	SQLITE_COUNT_PART string = `
+		SELECT %[2]s AS node_id, count(*) AS count
+		FROM %[1]s
+		WHERE %[3]s
+		GROUP BY %[2]s
+	`
+)
+
+// Node ids are integers or text in SQLite, which has no uuid type.
+var SQLITE_ID_COLUMN_TYPES = map[string]string{
+	"":        "integer",
+	INT_ID:    "integer",
+	STRING_ID: "text",
+	UUID_ID:   "text",
+}
+
+var SQLITE_INDEXES = []database.Index{
+	{Suffix: "_src_score", Columns: "src_id, score, id"},
+	{Suffix: "_dest_score", Columns: "dest_id, score, id"},
+	{Suffix: "_src_updated", Columns: "src_id, updated, id"},
+	{Suffix: "_dest_updated", Columns: "dest_id, updated, id"},
+}
+
+// sqliteTable quotes the name of a table, SQLite tables are named
+// after the full name of their edge type.
+func sqliteTable(tableName string) string {
+	return pq.QuoteIdentifier(tableName)
+}
+
+// Type a cursor key of the order column is cast back to in SQLite.
+var SQLITE_ORDER_COLUMNS = map[string]string{
+	"id":      "text",
+	"score":   "real",
+	"updated": "text",
+}
+
+// SQLiteStore keeps every edge type in its own table of a SQLite
+// database, laid out like the Postgres tables with `data` as JSON
+// text. Namespaced edge types are in tables named `namespace.name`.
+type SQLiteStore struct {
+	Db    *sqlx.DB
+	stats *statsCache
+}
+
+var (
+	_ EdgeStore = (*SQLiteStore)(nil)
+	_ SQLRunner = (*SQLiteStore)(nil)
+)
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+
+	db, err := sqlx.Open("sqlite3", path)
+
+	if err != nil {
+		return nil, err
+	}
+
+	// SQLite allows a single writer, one connection also keeps the
+	// data of `:memory:` databases around.
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(SQLITE_CREATE_SYSTEM_TABLES); err != nil {
+		db.Close()
+		return nil, err
+	}
+
+	return &SQLiteStore{Db: db, stats: newStatsCache()}, nil
+}
+
+func (store *SQLiteStore) Close() error {
+	return store.Db.Close()
+}
+
+func (store *SQLiteStore) CreateType(edgeType *EdgeType) error {
+
+	tableName := sqliteTable(edgeType.FullName())
+
+	if _, err := store.Db.Exec(fmt.Sprintf(SQLITE_CREATE_EDGE_TABLE, tableName, SQLITE_ID_COLUMN_TYPES[edgeType.Options.IdType])); err != nil {
+		return err
+	}
+
+	for _, index := range SQLITE_INDEXES {
+
+		indexName := sqliteTable(edgeType.FullName() + index.Suffix)
+
+		if _, err := store.Db.Exec(fmt.Sprintf(database.DML_CREATE_INDEX, indexName, tableName, index.Columns)); err != nil {
+			return err
+		}
+	}
+
+	_, err := store.Db.Exec(
+		SQLITE_TYPE_SAVE_PART, edgeType.Namespace, edgeType.Name, edgeType.SrcTypes,
+		edgeType.DestTypes, edgeType.Schema, edgeType.Options,
+	)
+
+	return err
+}
+
+// MigrateEdgeTables adds the `qualifier` column to the edge tables
+// created before edges had one, like database.MigrateEdgeTables does
+// on Postgres, and returns the names of the tables it migrated.
+func (store *SQLiteStore) MigrateEdgeTables() ([]string, error) {
+
+	tableNames := make([]string, 0)
+
+	if err := store.Db.Select(&tableNames, SQLITE_SELECT_UNQUALIFIED_TABLES); err != nil {
+		return nil, err
+	}
+
+	for _, tableName := range tableNames {
+
+		if _, err := store.Db.Exec(fmt.Sprintf(SQLITE_ADD_QUALIFIER, sqliteTable(tableName))); err != nil {
+			return nil, err
+		}
+	}
+
+	return tableNames, nil
+}
+
+func (store *SQLiteStore) GetType(edgeName string) (*EdgeType, error) {
+
+	namespace, name := database.SplitName(edgeName)
+
+	var edgeType EdgeType
+
+	err := store.Db.Get(&edgeType, TYPE_SELECT_PART+" WHERE namespace = ? AND name = ?", namespace, name)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &edgeType, nil
+}
+
+func (store *SQLiteStore) ListTypes() (*[]EdgeType, error) {
+
+	edgeTypes := make([]EdgeType, 0)
+
+	err := store.Db.Select(&edgeTypes, TYPE_SELECT_PART+" ORDER BY namespace, name")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &edgeTypes, nil
+}
+
+func (store *SQLiteStore) LoadStats(edgeType *EdgeType) error {
+
+	edgeName := edgeType.FullName()
+
+	stats, err := store.stats.load(edgeName, func() (*EdgeTypeStats, error) {
+		return countStats(store.Db, sqliteTable(edgeName))
+	})
+
+	if err != nil {
+		return err
+	}
+
+	edgeType.Stats = stats
+
+	return nil
+}
+
+func (store *SQLiteStore) Save(edgesPtr *[]Edge) ([]EdgeResult, error) {
+	return applyOperation(store, SAVE, edgesPtr)
+}
+
+func (store *SQLiteStore) Delete(edgesPtr *[]Edge) ([]EdgeResult, error) {
+	return applyOperation(store, DELETE, edgesPtr)
+}
+
+func (store *SQLiteStore) Apply(requested []Operation) ([][]EdgeResult, error) {
+
+	operations, err := mirrorOperations(store, requested)
+
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := store.Db.Beginx()
+
+	if err != nil {
+		return nil, err
+	}
+
+	defer tx.Rollback()
+
+	err = checkTables(operations, sqliteHasTable(tx))
+
+	if err != nil {
+		return nil, err
+	}
+
+	// writes are serialized by the single connection to the database
+	err = checkPreconditions(operations, func(edgeName string, ids []string) (map[string]*Edge, error) {
+
+		query, valueArgs, err := sqlx.In(fmt.Sprintf(SQLITE_SELECT_PART, sqliteTable(edgeName), "id")+" WHERE id IN (?)", ids)
+
+		if err != nil {
+			return nil, err
+		}
+
+		rows := make([]cursorEdge, 0)
+
+		if err := tx.Select(&rows, query, valueArgs...); err != nil {
+			return nil, err
+		}
+
+		edges := make(map[string]*Edge, len(rows))
+
+		for idx := range rows {
+			edges[rows[idx].Id] = &rows[idx].Edge
+		}
+
+		return edges, nil
+	})
+
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]EdgeResult, len(operations))
+
+	for idx, operation := range operations {
+
+		if operation.Action == DELETE {
+			deleted := deletedEdges(*operation.Edges)
+			results[idx], err = execEdges(tx, DELETE, &deleted, SQLITE_DELETE_PART, sqliteDeleteArgs)
+		} else {
+			results[idx], err = execEdges(tx, SAVE, operation.Edges, SQLITE_SAVE_PART, sqliteSaveArgs)
+		}
+
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+
+	store.stats.forget(operations)
+
+	return requestResults(requested, results), nil
+}
+
+func sqliteSaveArgs(edge *Edge) ([]interface{}, error) {
+
+	data, err := sqliteJson(edge.Data)
+
+	if err != nil {
+		return nil, err
+	}
+
+	return []interface{}{
+		edge.DbId(), edge.SrcId, edge.SrcType, edge.DestId, edge.DestType,
+		edge.Qualifier, edge.Score, data, edge.Status, sqliteTime(edge.Updated),
+	}, nil
+}
+
+func sqliteDeleteArgs(edge *Edge) ([]interface{}, error) {
+
+	return []interface{}{
+		edge.DbId(), edge.SrcId, edge.SrcType, edge.DestId, edge.DestType,
+		edge.Qualifier, edge.Status, sqliteTime(edge.Updated),
+	}, nil
+}
+
+// execEdges runs the statement for the latest write of every edge,
+// in the transaction. Statements that lose to the stored edge do not
+// change any row.
+func execEdges(tx *sqlx.Tx, action string, edgesPtr *[]Edge, statement string, edgeArgs func(*Edge) ([]interface{}, error)) ([]EdgeResult, error) {
+
+	outcomes := make(map[edgeKey]string, len(*edgesPtr))
+
+	for edgeName, edges := range GroupByEdgeName(edgesPtr) {
+
+		tableName := sqliteTable(edgeName)
+
+		stmt, err := tx.Preparex(fmt.Sprintf(statement, tableName, winsPart(tableName)))
+
+		if err != nil {
+			return nil, err
+		}
+
+		for _, edge := range latestEdges(edges) {
+
+			outcome, err := execEdge(tx, stmt, action, tableName, &edge, edgeArgs)
+
+			if err != nil {
+				stmt.Close()
+				return nil, err
+			}
+
+			outcomes[edgeKey{edgeName, edge.DbId()}] = outcome
+		}
+
+		stmt.Close()
+	}
+
+	return edgeResults(*edgesPtr, outcomes), nil
+}
+
+func execEdge(tx *sqlx.Tx, stmt *sqlx.Stmt, action string, tableName string, edge *Edge, edgeArgs func(*Edge) ([]interface{}, error)) (string, error) {
+
+	valueArgs, err := edgeArgs(edge)
+
+	if err != nil {
+		return "", err
+	}
+
+	var status string
+
+	err = tx.Get(&status, "SELECT status FROM "+tableName+" WHERE id = ?", edge.DbId())
+
+	existed := err == nil
+
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	res, err := stmt.Exec(valueArgs...)
+
+	if err != nil {
+		return "", err
+	}
+
+	written, err := res.RowsAffected()
+
+	if err != nil {
+		return "", err
+	}
+
+	return writeOutcome(action, existed, status, written > 0), nil
+}
+
+func (store *SQLiteStore) Get(edge *Edge) (*Edge, error) {
+
+	edgeName := *edge.Name
+
+	var row cursorEdge
+
+	err := store.Db.Get(&row, fmt.Sprintf(SQLITE_SELECT_PART, sqliteTable(edgeName), "id")+" WHERE id = ?", edge.DbId())
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	row.Edge.Name = &edgeName
+
+	return &row.Edge, nil
+}
+
+func (store *SQLiteStore) List(query *EdgeQuery) (*[]Edge, string, error) {
+
+	sql, valueArgs, err := sqliteListSQL(query)
+
+	if err != nil {
+		return nil, "", err
+	}
+
+	if _, err := findEdge(store.lookupEdge, *query.Name); err != nil {
+		return nil, "", err
+	}
+
+	rows := make([]cursorEdge, 0)
+
+	if err := store.Db.Select(&rows, sql, valueArgs...); err != nil {
+		return nil, "", err
+	}
+
+	nextCursor := ""
+
+	if len(rows) > query.PageSize() {
+		rows = rows[:query.PageSize()]
+
+		last := rows[len(rows)-1]
+
+		cursor := Cursor{
+			OrderBy: query.OrderBy,
+			Key:     last.CursorKey,
+			Id:      last.Id,
+		}
+
+		nextCursor = cursor.Encode()
+	}
+
+	edgeList := make([]Edge, len(rows))
+
+	for idx, row := range rows {
+		edgeList[idx] = row.Edge
+		edgeList[idx].Name = query.Name
+	}
+
+	return &edgeList, nextCursor, nil
+}
+
+func (store *SQLiteStore) Count(query *CountQuery) (map[NodeId]int64, error) {
+
+	if err := query.Validate(); err != nil {
+		return nil, err
+	}
+
+	if _, err := findEdge(store.lookupEdge, *query.Name); err != nil {
+		return nil, err
+	}
+
+	column, nodeIds := query.nodeColumn()
+
+	conditions := []string{column + " IN (?)"}
+	valueArgs := []interface{}{nodeIds}
+
+	if query.SrcType != nil {
+		conditions = append(conditions, "src_type = ?")
+		valueArgs = append(valueArgs, *query.SrcType)
+	}
+
+	if query.DestType != nil {
+		conditions = append(conditions, "dest_type = ?")
+		valueArgs = append(valueArgs, *query.DestType)
+	}
+
+	conditions = append(conditions, "status IN (?)")
+	valueArgs = append(valueArgs, queryStatuses(query.Status))
+
+	sql, valueArgs, err := sqlx.In(
+		fmt.Sprintf(SQLITE_COUNT_PART, sqliteTable(*query.Name), column, strings.Join(conditions, " AND ")),
+		valueArgs...,
+	)
+
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]nodeCount, 0)
+
+	if err := store.Db.Select(&rows, sql, valueArgs...); err != nil {
+		return nil, err
+	}
+
+	counts := make(map[NodeId]int64, len(nodeIds))
+
+	for _, nodeId := range nodeIds {
+		counts[nodeId] = 0
+	}
+
+	for _, row := range rows {
+		counts[row.NodeId] = row.Count
+	}
+
+	return counts, nil
+}
+
+// lookupEdge is the edgeLookup of the edge tables of the database.
+func (store *SQLiteStore) lookupEdge(edgeName string) (*EdgeType, bool, error) {
+
+	exists, err := sqliteHasTable(store.Db)(edgeName)
+
+	if err != nil || !exists {
+		return nil, false, err
+	}
+
+	edgeType, err := store.GetType(edgeName)
+
+	return edgeType, true, err
+}
+
+// sqliteHasTable tells if the table of an edge exists.
+func sqliteHasTable(db sqlx.Queryer) func(edgeName string) (bool, error) {
+
+	return func(edgeName string) (bool, error) {
+
+		var count int
+
+		err := sqlx.Get(db, &count, "SELECT count(*) FROM sqlite_master WHERE type = 'table' AND name = ?", edgeName)
+
+		return count > 0, err
+	}
+}
+
+func (store *SQLiteStore) RunQuery(query string) (*[]Edge, error) {
+	return RunQuery(store.Db, query)
+}
+
+// sqliteListSQL compiles an edge query like EdgeQuery.ToSQL does, in
+// the SQL of SQLite.
+func sqliteListSQL(query *EdgeQuery) (string, []interface{}, error) {
+
+	if err := query.Validate(); err != nil {
+		return "", nil, err
+	}
+
+	conditions := make([]string, 0)
+	valueArgs := make([]interface{}, 0)
+
+	addCondition := func(condition string, value interface{}) {
+		valueArgs = append(valueArgs, value)
+		conditions = append(conditions, condition)
+	}
+
+	if len(query.SrcIds) > 0 {
+		addCondition("src_id IN (?)", query.SrcIds)
+	}
+
+	if len(query.DestIds) > 0 {
+		addCondition("dest_id IN (?)", query.DestIds)
+	}
+
+	if query.SrcType != nil {
+		addCondition("src_type = ?", *query.SrcType)
+	}
+
+	if query.DestType != nil {
+		addCondition("dest_type = ?", *query.DestType)
+	}
+
+	if len(query.Qualifiers) > 0 {
+		addCondition("qualifier IN (?)", query.Qualifiers)
+	}
+
+	addCondition("status IN (?)", queryStatuses(query.Status))
+
+	if query.Score != nil && query.Score.Min != nil {
+		addCondition("score >= ?", *query.Score.Min)
+	}
+
+	if query.Score != nil && query.Score.Max != nil {
+		addCondition("score <= ?", *query.Score.Max)
+	}
+
+	if query.Updated != nil && query.Updated.From != nil {
+		addCondition("updated >= ?", sqliteTime(query.Updated.From))
+	}
+
+	if query.Updated != nil && query.Updated.To != nil {
+		addCondition("updated < ?", sqliteTime(query.Updated.To))
+	}
+
+	column, direction := query.orderColumn()
+
+	if column != "id" {
+		conditions = append(conditions, column+" IS NOT NULL")
+	}
+
+	if query.Cursor != "" {
+		cursor, _ := DecodeCursor(query.Cursor)
+
+		comparison := ">"
+
+		if direction == "DESC" {
+			comparison = "<"
+		}
+
+		valueArgs = append(valueArgs, cursor.Key, cursor.Id)
+
+		conditions = append(conditions, fmt.Sprintf(
+			"(%s, id) %s (CAST(? AS %s), ?)",
+			column, comparison, SQLITE_ORDER_COLUMNS[column],
+		))
+	}
+
+	sql := fmt.Sprintf(SQLITE_SELECT_PART, sqliteTable(*query.Name), column)
+
+	sql = sql + " WHERE " + strings.Join(conditions, " AND ")
+
+	sql = sql + fmt.Sprintf(" ORDER BY %[1]s %[2]s, id %[2]s LIMIT ?", column, direction)
+
+	valueArgs = append(valueArgs, query.PageSize()+1)
+
+	return sqlx.In(sql, valueArgs...)
+}
+
+// queryStatuses are the statuses a query matches, active by default.
+func queryStatuses(statuses []string) []string {
+
+	if len(statuses) == 0 {
+		return []string{ACTIVE}
+	}
+
+	return statuses
+}
+
+func sqliteTime(value *time.Time) interface{} {
+
+	if value == nil {
+		return nil
+	}
+
+	return value.UTC().Format(SQLITE_TIME_FORMAT)
+}
+
+// sqliteJson saves `data` as JSON text, instead of the bytes of
+// Data.Value, so it can be read with the JSON functions of SQLite.
+func sqliteJson(data *Data) (interface{}, error) {
+
+	value, err := data.Value()
+
+	if value == nil || err != nil {
+		return nil, err
+	}
+
+	return string(value.([]byte)), nil
+}
@@ -0,0 +1,104 @@
+package models
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Stats of edge tables are counted by scanning the table, and are
+// kept for STATS_TTL, so listing the edge types does not scan every
+// table on each request. The stats served are not live, they can be
+// up to STATS_TTL out of date. A store forgets the stats of the edge
+// types it writes, other processes see them up to STATS_TTL late.
+const STATS_TTL time.Duration = time.Minute
+
+type cachedStats struct {
+	stats  *EdgeTypeStats
+	loaded time.Time
+}
+
+// statsCache keeps the stats of edge tables. Every forget of an edge
+// type bumps its generation, stats counted while it changed are not
+// kept, as the count may have missed the write.
+type statsCache struct {
+	lock        sync.Mutex
+	entries     map[string]cachedStats
+	generations map[string]uint64
+	now         func() time.Time
+}
+
+func newStatsCache() *statsCache {
+	return &statsCache{
+		entries:     make(map[string]cachedStats),
+		generations: make(map[string]uint64),
+		now:         time.Now,
+	}
+}
+
+// load returns the stats of the edge table, counting them when they
+// are not kept or are older than STATS_TTL.
+func (cache *statsCache) load(edgeName string, count func() (*EdgeTypeStats, error)) (*EdgeTypeStats, error) {
+
+	cache.lock.Lock()
+	cached, ok := cache.entries[edgeName]
+	generation := cache.generations[edgeName]
+	cache.lock.Unlock()
+
+	if ok && cache.now().Sub(cached.loaded) < STATS_TTL {
+		return cached.stats, nil
+	}
+
+	loaded := cache.now()
+
+	stats, err := count()
+
+	if err != nil {
+		return nil, err
+	}
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	if cache.generations[edgeName] == generation {
+		cache.entries[edgeName] = cachedStats{stats: stats, loaded: loaded}
+	}
+
+	return stats, nil
+}
+
+// forget drops the stats of the edge types of the operations, and
+// the stats being counted for them.
+func (cache *statsCache) forget(operations []Operation) {
+
+	cache.lock.Lock()
+	defer cache.lock.Unlock()
+
+	for _, operation := range operations {
+		for edgeName := range GroupByEdgeName(operation.Edges) {
+			delete(cache.entries, edgeName)
+			cache.generations[edgeName]++
+		}
+	}
+}
+
+// countStats counts the rows of the edge table, by status.
+func countStats(db sqlx.Queryer, table string) (*EdgeTypeStats, error) {
+
+	rows := make([]statusCount, 0)
+
+	if err := sqlx.Select(db, &rows, fmt.Sprintf(TYPE_STATS_PART, table)); err != nil {
+		return nil, err
+	}
+
+	stats := &EdgeTypeStats{Statuses: make(map[string]int64)}
+
+	for _, row := range rows {
+		stats.Rows += row.Count
+		stats.Statuses[row.Status] = row.Count
+	}
+
+	return stats, nil
+}
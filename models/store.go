@@ -0,0 +1,73 @@
+package models
+
+// EdgeStore is the storage backend the HTTP router and the Pubsub
+// listener work with. Saves and deletes keep the last write, by
+// `updated`, of every edge and deletes are soft, through `status`.
+type EdgeStore interface {
+	// CreateType creates the storage of an edge type & registers it.
+	CreateType(edgeType *EdgeType) error
+
+	// GetType returns nil when the edge type is not registered.
+	GetType(edgeName string) (*EdgeType, error)
+
+	ListTypes() (*[]EdgeType, error)
+
+	// LoadStats sets the live row counts of the edge type.
+	LoadStats(edgeType *EdgeType) error
+
+	// Save & Delete return the result of every edge, in order.
+	Save(edgesPtr *[]Edge) ([]EdgeResult, error)
+
+	Delete(edgesPtr *[]Edge) ([]EdgeResult, error)
+
+	// Apply writes the operations in order, either all of them or
+	// none of them, and returns the results of every operation.
+	Apply(operations []Operation) ([][]EdgeResult, error)
+
+	// Get returns the stored edge with the name & identity of the
+	// edge, or nil when there is none.
+	Get(edge *Edge) (*Edge, error)
+
+	// List returns a page of edges and the cursor of the next page.
+	List(query *EdgeQuery) (*[]Edge, string, error)
+
+	Count(query *CountQuery) (map[NodeId]int64, error)
+
+	Close() error
+}
+
+// The queries below are only supported by some backends, handlers
+// answer with a 501 when the store does not implement them.
+
+type SetCombiner interface {
+	CombineSets(query *SetQuery) (*SetResult, error)
+}
+
+type Traverser interface {
+	Traverse(traversal *Traversal) (*[]ReachedNode, error)
+}
+
+type FeedStore interface {
+	CreateFeed(feed *Feed) error
+	GetFeed(feedName string) (*Feed, error)
+	ReadFeed(feed *Feed, userId int64, limit int, cursor string) (*[]Edge, string, error)
+}
+
+type SQLRunner interface {
+	RunQuery(query string) (*[]Edge, error)
+}
+
+// CounterStore keeps the counters of edge types created with
+// `counters`, see EdgeTypeOptions.
+type CounterStore interface {
+	RebuildCounts(edgeName string) error
+}
+
+// KeyStore keeps the API keys, see ApiKey. CreateKey returns the
+// token of the new key.
+type KeyStore interface {
+	CreateKey(key *ApiKey) (string, error)
+	GetKey(id string) (*ApiKey, error)
+	ListKeys() (*[]ApiKey, error)
+	RevokeKey(id string) (bool, error)
+}
@@ -0,0 +1,369 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	DEFAULT_FAN_OUT    int = 100
+	MAX_FAN_OUT        int = 1000
+	MAX_HOP_REPEAT     int = 32
+	MAX_TRAVERSE_NODES int = 10000
+
+	// Follows the edges of a frontier, keeping the `fan_out` highest
+	// scored edges of every node.
+	HOP_PART string = `
+		SELECT node_id, neighbor_id, neighbor_type
+		FROM (
+		  SELECT
+		    %[2]s AS node_id,
+		    %[3]s AS neighbor_id,
+		    %[4]s AS neighbor_type,
+		    row_number() OVER (PARTITION BY %[2]s ORDER BY score DESC NULLS LAST, id) AS rank
+		  FROM %[1]s
+		  WHERE %[5]s
+		) AS ranked
+		WHERE rank <= $%[6]d
+		ORDER BY node_id, rank
+	`
+)
+
+// Hop follows one edge type from every node of the frontier, `out`
+// along the edges of the node or `in` against them. A hop with
+// `repeat` is followed again from the nodes it reached, until
+// nothing new is reached, e.g. to walk `child_page` edges up to the
+// root page.
+type Hop struct {
+	Name      *string  `json:"name"`
+	Direction string   `json:"direction"`
+	SrcType   *string  `json:"src_type"`
+	DestType  *string  `json:"dest_type"`
+	Status    []string `json:"status"`
+	FanOut    int      `json:"fan_out"`
+	Repeat    int      `json:"repeat"`
+}
+
+// Traversal walks the hops in order from the start nodes. Nodes are
+// visited once, by the shortest path, so following `follow` twice
+// returns friends of friends that are not already friends. Nodes
+// are told apart within the edge type of the hop that reached them,
+// e.g. tag 7 reached by `tag` is not user 7 reached by `follow`, and
+// the start nodes are of the edge type of the first hop.
+type Traversal struct {
+	Start []NodeId `json:"start"`
+	Hops  []Hop    `json:"hops"`
+	Limit int      `json:"limit"`
+}
+
+// ReachedNode is a node reached by the last hop, with the ids of
+// the nodes on the path from the start node to it.
+type ReachedNode struct {
+	Id    NodeId   `json:"id"`
+	Type  *string  `json:"type,omitempty"`
+	Depth int      `json:"depth"`
+	Path  []NodeId `json:"path"`
+}
+
+type hopRow struct {
+	NodeId       NodeId  `db:"node_id"`
+	NeighborId   NodeId  `db:"neighbor_id"`
+	NeighborType *string `db:"neighbor_type"`
+}
+
+// visitKey is a node in the id space of an edge type.
+type visitKey struct {
+	EdgeName string
+	Id       NodeId
+}
+
+// hopEdges returns the edges of the hop from the frontier, ordered
+// by node & by rank, up to the fan out of every node.
+type hopEdges func(hop *Hop, frontier []NodeId) ([]hopRow, error)
+
+func (traversal *Traversal) Validate() error {
+
+	if len(traversal.Start) == 0 {
+		return &ValidationError{"start", "Traversal needs atleast one `start` node"}
+	}
+
+	if len(traversal.Hops) == 0 {
+		return &ValidationError{"hops", "Traversal needs atleast one hop"}
+	}
+
+	for idx, hop := range traversal.Hops {
+
+		if hop.Name == nil || *hop.Name == "" {
+			return &ValidationError{fmt.Sprintf("hops.%d.name", idx), fmt.Sprintf("Hop at %d does not have `name`", idx)}
+		}
+
+		if hop.Direction != OUT && hop.Direction != IN {
+			return &ValidationError{fmt.Sprintf("hops.%d.direction", idx), "Direction must be `out` or `in`"}
+		}
+
+		if hop.FanOut < 0 || hop.FanOut > MAX_FAN_OUT {
+			return &ValidationError{fmt.Sprintf("hops.%d.fan_out", idx), fmt.Sprintf("Fan out cannot be negative or more than %d", MAX_FAN_OUT)}
+		}
+
+		if hop.Repeat < 0 || hop.Repeat > MAX_HOP_REPEAT {
+			return &ValidationError{fmt.Sprintf("hops.%d.repeat", idx), fmt.Sprintf("Repeat cannot be negative or more than %d", MAX_HOP_REPEAT)}
+		}
+	}
+
+	if traversal.Limit < 0 || traversal.Limit > MAX_QUERY_LIMIT {
+		return &ValidationError{"limit", fmt.Sprintf("Limit cannot be negative or more than %d", MAX_QUERY_LIMIT)}
+	}
+
+	return nil
+}
+
+func (hop *Hop) ToSQL(frontier []NodeId) (string, []interface{}) {
+
+	nodeColumn, neighborColumn, neighborType := "src_id", "dest_id", "dest_type"
+
+	if hop.Direction == IN {
+		nodeColumn, neighborColumn, neighborType = "dest_id", "src_id", "src_type"
+	}
+
+	conditions := make([]string, 0)
+	valueArgs := make([]interface{}, 0)
+
+	addCondition := func(condition string, value interface{}) {
+		valueArgs = append(valueArgs, value)
+		conditions = append(conditions, fmt.Sprintf(condition, len(valueArgs)))
+	}
+
+	addCondition(nodeColumn+" = ANY($%d)", pq.Array(frontier))
+
+	if hop.SrcType != nil {
+		addCondition("src_type = $%d", *hop.SrcType)
+	}
+
+	if hop.DestType != nil {
+		addCondition("dest_type = $%d", *hop.DestType)
+	}
+
+	if len(hop.Status) > 0 {
+		addCondition("status = ANY($%d)", pq.Array(hop.Status))
+	} else {
+		addCondition("status = $%d", ACTIVE)
+	}
+
+	valueArgs = append(valueArgs, hop.fanOut())
+
+	sql := fmt.Sprintf(
+		HOP_PART, database.QuoteName(*hop.Name), nodeColumn, neighborColumn, neighborType,
+		strings.Join(conditions, " AND "), len(valueArgs),
+	)
+
+	return sql, valueArgs
+}
+
+func (hop *Hop) fanOut() int {
+
+	if hop.FanOut == 0 {
+		return DEFAULT_FAN_OUT
+	}
+
+	return hop.FanOut
+}
+
+func Traverse(db *sqlx.DB, traversal *Traversal) (*[]ReachedNode, error) {
+
+	if err := traversal.Validate(); err != nil {
+		return nil, err
+	}
+
+	edgeType, err := hopTypes(traversal, lookupEdge(db))
+
+	if err != nil {
+		return nil, err
+	}
+
+	return walk(traversal, edgeType, func(hop *Hop, frontier []NodeId) ([]hopRow, error) {
+
+		sql, valueArgs := hop.ToSQL(frontier)
+
+		rows := make([]hopRow, 0)
+
+		if err := db.Select(&rows, sql, valueArgs...); err != nil {
+			return nil, err
+		}
+
+		return rows, nil
+	})
+}
+
+// hopTypes makes sure the edge table of every hop exists before the
+// traversal starts, and returns the edge type of the first hop.
+func hopTypes(traversal *Traversal, lookup edgeLookup) (*EdgeType, error) {
+
+	var first *EdgeType
+
+	for idx, hop := range traversal.Hops {
+
+		edgeType, err := findEdge(lookup, *hop.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		if idx == 0 {
+			first = edgeType
+		}
+	}
+
+	return first, nil
+}
+
+// startNodes checks the start nodes against the id type of the first
+// hop, which is nil when it is not registered, and puts them in the
+// Canonical form the edges of the hop return them in.
+func startNodes(traversal *Traversal, edgeType *EdgeType) ([]NodeId, error) {
+
+	options := EdgeTypeOptions{}
+
+	if edgeType != nil {
+		options = edgeType.Options
+	}
+
+	start := make([]NodeId, len(traversal.Start))
+
+	for idx, nodeId := range traversal.Start {
+
+		start[idx] = nodeId.Canonical(options.IdType)
+
+		if !start[idx].Valid(options.IdType) {
+			return nil, &ValidationError{
+				fmt.Sprintf("start.%d", idx),
+				fmt.Sprintf("Start node at %d must have an id of %s", idx, options.IdColumnType()),
+			}
+		}
+	}
+
+	return start, nil
+}
+
+// walk follows the hops of the traversal from its start nodes, with
+// the edges of every hop returned by follow.
+func walk(traversal *Traversal, edgeType *EdgeType, follow hopEdges) (*[]ReachedNode, error) {
+
+	start, err := startNodes(traversal, edgeType)
+
+	if err != nil {
+		return nil, err
+	}
+
+	visited := make(map[visitKey]*ReachedNode)
+	parents := make(map[visitKey]visitKey)
+
+	// the edge type the nodes of the frontier were reached by
+	edgeName := *traversal.Hops[0].Name
+
+	frontier := make([]NodeId, 0, len(start))
+
+	for _, nodeId := range start {
+
+		key := visitKey{edgeName, nodeId}
+
+		if _, ok := visited[key]; !ok {
+			visited[key] = &ReachedNode{Id: nodeId}
+			frontier = append(frontier, nodeId)
+		}
+	}
+
+	var reached []visitKey
+
+	for idx := range traversal.Hops {
+
+		hop := &traversal.Hops[idx]
+
+		reached = make([]visitKey, 0)
+
+		for step := 0; step <= hop.Repeat && len(frontier) > 0; step++ {
+
+			rows, err := follow(hop, frontier)
+
+			if err != nil {
+				return nil, err
+			}
+
+			frontier = make([]NodeId, 0)
+
+			for _, row := range rows {
+
+				key := visitKey{*hop.Name, row.NeighborId}
+
+				if _, ok := visited[key]; ok {
+					continue
+				}
+
+				parentKey := visitKey{edgeName, row.NodeId}
+				parent, ok := visited[parentKey]
+
+				if !ok {
+					continue
+				}
+
+				if len(visited) >= MAX_TRAVERSE_NODES {
+					break
+				}
+
+				visited[key] = &ReachedNode{
+					Id:    row.NeighborId,
+					Type:  row.NeighborType,
+					Depth: parent.Depth + 1,
+				}
+				parents[key] = parentKey
+
+				frontier = append(frontier, row.NeighborId)
+				reached = append(reached, key)
+			}
+
+			// repeats continue from the nodes of the hop's edge type
+			edgeName = *hop.Name
+		}
+
+		edgeName = *hop.Name
+
+		// the next hop continues from everything this hop reached,
+		// over all of its repeats
+		frontier = make([]NodeId, len(reached))
+
+		for nodeIdx, key := range reached {
+			frontier[nodeIdx] = key.Id
+		}
+	}
+
+	limit := traversal.Limit
+
+	if limit == 0 {
+		limit = DEFAULT_QUERY_LIMIT
+	}
+
+	if len(reached) > limit {
+		reached = reached[:limit]
+	}
+
+	nodes := make([]ReachedNode, len(reached))
+
+	for idx, key := range reached {
+
+		node := *visited[key]
+
+		node.Path = make([]NodeId, node.Depth+1)
+
+		for pathKey, depth := key, node.Depth; depth >= 0; depth-- {
+			node.Path[depth] = pathKey.Id
+			pathKey = parents[pathKey]
+		}
+
+		nodes[idx] = node
+	}
+
+	return &nodes, nil
+}
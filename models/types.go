@@ -0,0 +1,468 @@
+package models
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+	"github.com/sonnes/loki/database"
+)
+
+const (
+	TYPE_SAVE_PART string = `
+		INSERT INTO loki_edge_types (namespace, name, src_types, dest_types, schema, options)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		ON CONFLICT (namespace, name) DO UPDATE
+			SET
+				src_types = EXCLUDED.src_types,
+				dest_types = EXCLUDED.dest_types,
+				schema = EXCLUDED.schema,
+				options = EXCLUDED.options
+	`
+
+	TYPE_SELECT_PART string = `
+		SELECT namespace, name, src_types, dest_types, schema, options, created
+		FROM loki_edge_types
+	`
+
+	TYPE_STATS_PART string = `
+		SELECT COALESCE(status, '') AS status, count(*) AS count
+		FROM %s
+		GROUP BY status
+	`
+
+	NAME_GRAMMAR_MESSAGE string = "Names must be lowercase letters, digits & `_`, start with a letter or `_`, be at most 40 characters long, not end with `_counts` & not start with `loki_`, `feed_` or `sqlite_`"
+)
+
+// UnknownEdgeError is returned by stores for writes of edges whose
+// edge table does not exist.
+type UnknownEdgeError struct {
+	Name string
+}
+
+func (err *UnknownEdgeError) Error() string {
+	return fmt.Sprintf("%s - edge type does not exist", err.Name)
+}
+
+// edgeLookup looks up the edge type of an edge, which is nil when it
+// is not registered, & tells if its table exists.
+type edgeLookup func(edgeName string) (*EdgeType, bool, error)
+
+// checkTables makes sure the edge table of every edge of the
+// operations exists, before any of them is written. Names that do
+// not follow the grammar of names are never looked up.
+func checkTables(operations []Operation, hasTable func(edgeName string) (bool, error)) error {
+
+	checked := make(map[string]bool)
+
+	for _, operation := range operations {
+		for edgeName := range GroupByEdgeName(operation.Edges) {
+
+			if checked[edgeName] {
+				continue
+			}
+
+			if !database.ValidTableName(edgeName) {
+				return &UnknownEdgeError{edgeName}
+			}
+
+			exists, err := hasTable(edgeName)
+
+			if err != nil {
+				return err
+			}
+
+			if !exists {
+				return &UnknownEdgeError{edgeName}
+			}
+
+			checked[edgeName] = true
+		}
+	}
+
+	return nil
+}
+
+// EdgeType is the registered definition of an edge table. Edges of
+// a type with `src_types` or `dest_types` can only connect nodes of
+// those types.
+type EdgeType struct {
+	Namespace string          `json:"namespace" db:"namespace"`
+	Name      string          `json:"name" db:"name"`
+	SrcTypes  pq.StringArray  `json:"src_types" db:"src_types"`
+	DestTypes pq.StringArray  `json:"dest_types" db:"dest_types"`
+	Schema    *Data           `json:"schema,omitempty" db:"schema"`
+	Options   EdgeTypeOptions `json:"options" db:"options"`
+	Created   *time.Time      `json:"created,omitempty" db:"created"`
+	Stats     *EdgeTypeStats  `json:"stats,omitempty" db:"-"`
+}
+
+// Edges of types with `qualified` are identified by a `qualifier`
+// along with their nodes, so there can be many edges between them.
+// `id_type` is the type of the node ids, see ID_COLUMN_TYPES.
+//
+// Every edge of a `symmetric` type is written along with its mirror,
+// from the destination to the source node, and the edges of a type
+// with an `inverse` along with their mirror on the inverse type.
+type EdgeTypeOptions struct {
+	Counters  bool   `json:"counters"`
+	Qualified bool   `json:"qualified"`
+	IdType    string `json:"id_type,omitempty"`
+	Symmetric bool   `json:"symmetric,omitempty"`
+	Inverse   string `json:"inverse,omitempty"`
+}
+
+func (options EdgeTypeOptions) IdColumnType() string {
+	return ID_COLUMN_TYPES[options.IdType]
+}
+
+func (options EdgeTypeOptions) Value() (driver.Value, error) {
+	return json.Marshal(options)
+}
+
+func (options *EdgeTypeOptions) Scan(src interface{}) error {
+
+	var data []byte
+	if b, ok := src.([]byte); ok {
+		data = b
+	} else if s, ok := src.(string); ok {
+		data = []byte(s)
+	} else if src == nil {
+		return nil
+	}
+	return json.Unmarshal(data, options)
+}
+
+// EdgeTypeStats are the live row counts of an edge table.
+type EdgeTypeStats struct {
+	Rows     int64            `json:"rows"`
+	Statuses map[string]int64 `json:"statuses"`
+}
+
+type statusCount struct {
+	Status string `db:"status"`
+	Count  int64  `db:"count"`
+}
+
+// FullName is the `namespace.name` the edges of the type are saved with.
+func (edgeType *EdgeType) FullName() string {
+	return database.QualifyName(edgeType.Namespace, edgeType.Name)
+}
+
+func RegisterEdgeType(db *sqlx.DB, edgeType *EdgeType) error {
+
+	_, err := db.Exec(
+		TYPE_SAVE_PART, edgeType.Namespace, edgeType.Name, edgeType.SrcTypes,
+		edgeType.DestTypes, edgeType.Schema, edgeType.Options,
+	)
+
+	return err
+}
+
+// GetEdgeType returns nil when the edge type is not registered.
+func GetEdgeType(db sqlx.Queryer, edgeName string) (*EdgeType, error) {
+
+	namespace, name := database.SplitName(edgeName)
+
+	var edgeType EdgeType
+
+	err := sqlx.Get(db, &edgeType, TYPE_SELECT_PART+" WHERE namespace = $1 AND name = $2", namespace, name)
+
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &edgeType, nil
+}
+
+// lookupEdge is the edgeLookup of the edge tables of the database.
+func lookupEdge(db sqlx.Queryer) edgeLookup {
+
+	return func(edgeName string) (*EdgeType, bool, error) {
+
+		exists, err := database.HasTable(db, edgeName)
+
+		if err != nil || !exists {
+			return nil, false, err
+		}
+
+		edgeType, err := GetEdgeType(db, edgeName)
+
+		return edgeType, true, err
+	}
+}
+
+// findEdge returns the edge type of an edge that is read, which is
+// nil when its table is not registered, or an UnknownEdgeError when
+// there is no edge table of the name.
+func findEdge(lookup edgeLookup, edgeName string) (*EdgeType, error) {
+
+	if !database.ValidTableName(edgeName) {
+		return nil, &UnknownEdgeError{edgeName}
+	}
+
+	edgeType, exists, err := lookup(edgeName)
+
+	if err != nil {
+		return nil, err
+	}
+
+	if !exists {
+		return nil, &UnknownEdgeError{edgeName}
+	}
+
+	return edgeType, nil
+}
+
+func ListEdgeTypes(db *sqlx.DB) (*[]EdgeType, error) {
+
+	edgeTypes := make([]EdgeType, 0)
+
+	err := db.Select(&edgeTypes, TYPE_SELECT_PART+" ORDER BY namespace, name")
+
+	if err != nil {
+		return nil, err
+	}
+
+	return &edgeTypes, nil
+}
+
+func containsString(list []string, value *string) bool {
+
+	for _, item := range list {
+		if value != nil && item == *value {
+			return true
+		}
+	}
+
+	return false
+}
+
+// ValidateEdges checks the edges against the definitions of their
+// registered types, including the JSON Schema of their `data`, and
+// puts their node ids in the Canonical form of the type. Edges of
+// types that are not registered only need the integer node ids of
+// their tables.
+func ValidateEdges(store EdgeStore, edgesPtr *[]Edge) error {
+	return validateEdges(store, edgesPtr, nil)
+}
+
+// validateEdges reports the errors of every edge at its position in
+// positions, which are the indexes of the edges when it is nil.
+func validateEdges(store EdgeStore, edgesPtr *[]Edge, positions []int) error {
+
+	edgeTypes, err := edgeTypesOf(store, edgesPtr)
+
+	if err != nil {
+		return err
+	}
+
+	schemaFields := make([]string, 0)
+
+	for edgeIdx := range *edgesPtr {
+
+		// the ids of the edges are made canonical in place
+		edge := &(*edgesPtr)[edgeIdx]
+		edgeType := edgeTypes[*edge.Name]
+		idx := position(positions, edgeIdx)
+
+		if edgeType == nil {
+
+			if err := validateNodeIds(EdgeTypeOptions{}, edge, idx); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := validateIdentity(edgeType, edge, idx); err != nil {
+			return err
+		}
+
+		if len(edgeType.SrcTypes) > 0 && !containsString(edgeType.SrcTypes, edge.SrcType) {
+			return &ValidationError{
+				fmt.Sprintf("edges.%d.src_type", idx),
+				fmt.Sprintf("Edge at %d must have a `src_type` of %v", idx, []string(edgeType.SrcTypes)),
+			}
+		}
+
+		if len(edgeType.DestTypes) > 0 && !containsString(edgeType.DestTypes, edge.DestType) {
+			return &ValidationError{
+				fmt.Sprintf("edges.%d.dest_type", idx),
+				fmt.Sprintf("Edge at %d must have a `dest_type` of %v", idx, []string(edgeType.DestTypes)),
+			}
+		}
+
+		if edgeType.Schema != nil {
+			fields, err := validateData(edgeType, edge, idx)
+
+			if err != nil {
+				return err
+			}
+
+			schemaFields = append(schemaFields, fields...)
+		}
+	}
+
+	if len(schemaFields) > 0 {
+		return &SchemaError{schemaErrorMessage(schemaFields), schemaFields}
+	}
+
+	return nil
+}
+
+// ValidateDeletes checks that the edges to delete are identified the
+// way their registered types identify edges.
+func ValidateDeletes(store EdgeStore, edgesPtr *[]Edge) error {
+	return validateDeletes(store, edgesPtr, nil)
+}
+
+func validateDeletes(store EdgeStore, edgesPtr *[]Edge, positions []int) error {
+
+	edgeTypes, err := edgeTypesOf(store, edgesPtr)
+
+	if err != nil {
+		return err
+	}
+
+	for edgeIdx := range *edgesPtr {
+
+		edge := &(*edgesPtr)[edgeIdx]
+		edgeType := edgeTypes[*edge.Name]
+		idx := position(positions, edgeIdx)
+
+		if edgeType == nil {
+
+			if err := validateNodeIds(EdgeTypeOptions{}, edge, idx); err != nil {
+				return err
+			}
+
+			continue
+		}
+
+		if err := validateIdentity(edgeType, edge, idx); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func position(positions []int, idx int) int {
+
+	if positions == nil {
+		return idx
+	}
+
+	return positions[idx]
+}
+
+// edgeTypesOf looks up the registered types of the edges, which are
+// nil for types that are not registered.
+func edgeTypesOf(store EdgeStore, edgesPtr *[]Edge) (map[string]*EdgeType, error) {
+
+	edgeTypes := make(map[string]*EdgeType)
+
+	for idx, edge := range *edgesPtr {
+
+		if edge.Name == nil {
+			return nil, &ValidationError{
+				fmt.Sprintf("edges.%d.name", idx),
+				fmt.Sprintf("Edge at %d does not have `name`", idx),
+			}
+		}
+
+		if _, ok := edgeTypes[*edge.Name]; ok {
+			continue
+		}
+
+		edgeType, err := store.GetType(*edge.Name)
+
+		if err != nil {
+			return nil, err
+		}
+
+		edgeTypes[*edge.Name] = edgeType
+	}
+
+	return edgeTypes, nil
+}
+
+// validateIdentity checks the node ids & the qualifier, which the
+// edge is identified by.
+func validateIdentity(edgeType *EdgeType, edge *Edge, idx int) error {
+
+	if err := validateNodeIds(edgeType.Options, edge, idx); err != nil {
+		return err
+	}
+
+	return validateQualifier(edgeType, edge, idx)
+}
+
+// validateNodeIds puts the node ids in their Canonical form & checks
+// them against the id type of the options, the ids of unregistered
+// types are integers.
+func validateNodeIds(options EdgeTypeOptions, edge *Edge, idx int) error {
+
+	edge.SrcId = edge.SrcId.Canonical(options.IdType)
+	edge.DestId = edge.DestId.Canonical(options.IdType)
+
+	idKind := options.IdColumnType()
+
+	if options.IdType == STRING_ID {
+		idKind = fmt.Sprintf("%s without `%s`", idKind, ID_SEPARATOR)
+	}
+
+	if !edge.SrcId.Valid(options.IdType) {
+		return &ValidationError{
+			fmt.Sprintf("edges.%d.src_id", idx),
+			fmt.Sprintf("Edge at %d must have a `src_id` of %s", idx, idKind),
+		}
+	}
+
+	if !edge.DestId.Valid(options.IdType) {
+		return &ValidationError{
+			fmt.Sprintf("edges.%d.dest_id", idx),
+			fmt.Sprintf("Edge at %d must have a `dest_id` of %s", idx, idKind),
+		}
+	}
+
+	return nil
+}
+
+func validateQualifier(edgeType *EdgeType, edge *Edge, idx int) error {
+
+	hasQualifier := edge.Qualifier != nil && *edge.Qualifier != ""
+
+	if edgeType.Options.Qualified && !hasQualifier {
+		return &ValidationError{
+			fmt.Sprintf("edges.%d.qualifier", idx),
+			fmt.Sprintf("Edge at %d must have a `qualifier`", idx),
+		}
+	}
+
+	if hasQualifier && strings.Contains(*edge.Qualifier, ID_SEPARATOR) {
+		return &ValidationError{
+			fmt.Sprintf("edges.%d.qualifier", idx),
+			fmt.Sprintf("Edge at %d cannot have a `qualifier` with `%s`", idx, ID_SEPARATOR),
+		}
+	}
+
+	if !edgeType.Options.Qualified && hasQualifier {
+		return &ValidationError{
+			fmt.Sprintf("edges.%d.qualifier", idx),
+			fmt.Sprintf("Edge at %d cannot have a `qualifier`, %s is not qualified", idx, edgeType.FullName()),
+		}
+	}
+
+	return nil
+}
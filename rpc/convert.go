@@ -0,0 +1,220 @@
+package rpc
+
+import (
+	"time"
+
+	"github.com/sonnes/loki/handlers"
+	"github.com/sonnes/loki/models"
+	"github.com/sonnes/loki/rpc/pb"
+	"google.golang.org/protobuf/types/known/structpb"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+// Messages are converted to the models the HTTP API decodes from
+// JSON. Empty names are left nil, like names missing from JSON.
+
+func optionalString(value string) *string {
+
+	if value == "" {
+		return nil
+	}
+
+	return &value
+}
+
+func toTime(timestamp *timestamppb.Timestamp) *time.Time {
+
+	if timestamp == nil {
+		return nil
+	}
+
+	value := timestamp.AsTime()
+
+	return &value
+}
+
+func fromTime(value *time.Time) *timestamppb.Timestamp {
+
+	if value == nil {
+		return nil
+	}
+
+	return timestamppb.New(*value)
+}
+
+func toData(data *structpb.Struct) *models.Data {
+
+	if data == nil {
+		return nil
+	}
+
+	value := models.Data(data.AsMap())
+
+	return &value
+}
+
+func fromData(data *models.Data) (*structpb.Struct, error) {
+
+	if data == nil {
+		return nil, nil
+	}
+
+	return structpb.NewStruct(*data)
+}
+
+func toNodeIds(ids []string) []models.NodeId {
+
+	nodeIds := make([]models.NodeId, len(ids))
+
+	for idx, id := range ids {
+		nodeIds[idx] = models.NodeId(id)
+	}
+
+	return nodeIds
+}
+
+func toEdges(edges []*pb.Edge) *[]models.Edge {
+
+	edgeList := make([]models.Edge, len(edges))
+
+	for idx, edge := range edges {
+
+		edgeList[idx] = models.Edge{
+			Id:        edge.Id,
+			Name:      optionalString(edge.Name),
+			SrcId:     models.NodeId(edge.SrcId),
+			SrcType:   edge.SrcType,
+			DestId:    models.NodeId(edge.DestId),
+			DestType:  edge.DestType,
+			Qualifier: edge.Qualifier,
+			Score:     edge.Score,
+			Data:      toData(edge.Data),
+			Status:    edge.Status,
+			Updated:   toTime(edge.Updated),
+		}
+
+		if edge.If != nil {
+			edgeList[idx].If = &models.Precondition{
+				Exists:  edge.If.Exists,
+				Status:  edge.If.Status,
+				Updated: toTime(edge.If.Updated),
+			}
+		}
+	}
+
+	return &edgeList
+}
+
+func fromEdges(edgeList []models.Edge) ([]*pb.Edge, error) {
+
+	edges := make([]*pb.Edge, len(edgeList))
+
+	for idx, edge := range edgeList {
+
+		data, err := fromData(edge.Data)
+
+		if err != nil {
+			return nil, err
+		}
+
+		edges[idx] = &pb.Edge{
+			Id:        edge.Id,
+			SrcId:     string(edge.SrcId),
+			SrcType:   edge.SrcType,
+			DestId:    string(edge.DestId),
+			DestType:  edge.DestType,
+			Qualifier: edge.Qualifier,
+			Score:     edge.Score,
+			Data:      data,
+			Status:    edge.Status,
+			Updated:   fromTime(edge.Updated),
+		}
+
+		if edge.Name != nil {
+			edges[idx].Name = *edge.Name
+		}
+	}
+
+	return edges, nil
+}
+
+func fromResults(results []models.EdgeResult) []*pb.EdgeResult {
+
+	edgeResults := make([]*pb.EdgeResult, len(results))
+
+	for idx, result := range results {
+
+		edgeResults[idx] = &pb.EdgeResult{Id: result.Id, Outcome: result.Outcome}
+
+		if result.Name != nil {
+			edgeResults[idx].Name = *result.Name
+		}
+	}
+
+	return edgeResults
+}
+
+func toInitEdgeRequest(request *pb.InitEdgeRequest) *handlers.InitEdgeRequest {
+
+	return &handlers.InitEdgeRequest{
+		Name:      request.Name,
+		Namespace: request.Namespace,
+		Counters:  request.Counters,
+		Qualified: request.Qualified,
+		Symmetric: request.Symmetric,
+		Inverse:   request.Inverse,
+		IdType:    request.IdType,
+		SrcTypes:  request.SrcTypes,
+		DestTypes: request.DestTypes,
+		Schema:    toData(request.Schema),
+	}
+}
+
+func toEdgeQuery(request *pb.QueryRequest) *models.EdgeQuery {
+
+	query := &models.EdgeQuery{
+		Name:       optionalString(request.Name),
+		SrcIds:     toNodeIds(request.SrcIds),
+		DestIds:    toNodeIds(request.DestIds),
+		SrcType:    request.SrcType,
+		DestType:   request.DestType,
+		Qualifiers: request.Qualifiers,
+		Status:     request.Statuses,
+		OrderBy:    request.OrderBy,
+		Limit:      int(request.Limit),
+		Cursor:     request.Cursor,
+	}
+
+	if request.Score != nil {
+		query.Score = &models.ScoreRange{Min: request.Score.Min, Max: request.Score.Max}
+	}
+
+	if request.Updated != nil {
+		query.Updated = &models.TimeRange{From: toTime(request.Updated.From), To: toTime(request.Updated.To)}
+	}
+
+	return query
+}
+
+func toCountQuery(request *pb.CountRequest) *models.CountQuery {
+
+	return &models.CountQuery{
+		Name:     optionalString(request.Name),
+		SrcIds:   toNodeIds(request.SrcIds),
+		DestIds:  toNodeIds(request.DestIds),
+		SrcType:  request.SrcType,
+		DestType: request.DestType,
+		Status:   request.Statuses,
+	}
+}
+
+func toBatch(request *pb.BatchRequest) *models.Batch {
+
+	operations := make([]models.Operation, len(request.Operations))
+
+	for idx, operation := range request.Operations {
+		operations[idx] = models.Operation{Action: operation.Action, Edges: toEdges(operation.Edges)}
+	}
+
+	return &models.Batch{Namespace: request.Namespace, Operations: operations}
+}
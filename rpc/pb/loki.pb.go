@@ -0,0 +1,1379 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: loki.proto
+
+package pb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	structpb "google.golang.org/protobuf/types/known/structpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type Edge struct {
+	state     protoimpl.MessageState `protogen:"open.v1"`
+	Id        string                 `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name      string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	SrcId     string                 `protobuf:"bytes,3,opt,name=src_id,json=srcId,proto3" json:"src_id,omitempty"`
+	SrcType   *string                `protobuf:"bytes,4,opt,name=src_type,json=srcType,proto3,oneof" json:"src_type,omitempty"`
+	DestId    string                 `protobuf:"bytes,5,opt,name=dest_id,json=destId,proto3" json:"dest_id,omitempty"`
+	DestType  *string                `protobuf:"bytes,6,opt,name=dest_type,json=destType,proto3,oneof" json:"dest_type,omitempty"`
+	Qualifier *string                `protobuf:"bytes,7,opt,name=qualifier,proto3,oneof" json:"qualifier,omitempty"`
+	Score     float32                `protobuf:"fixed32,8,opt,name=score,proto3" json:"score,omitempty"`
+	Data      *structpb.Struct       `protobuf:"bytes,9,opt,name=data,proto3" json:"data,omitempty"`
+	Status    string                 `protobuf:"bytes,10,opt,name=status,proto3" json:"status,omitempty"`
+	Updated   *timestamppb.Timestamp `protobuf:"bytes,11,opt,name=updated,proto3" json:"updated,omitempty"`
+	// only written when the stored edge meets the precondition
+	If            *Precondition `protobuf:"bytes,12,opt,name=if,proto3" json:"if,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Edge) Reset() {
+	*x = Edge{}
+	mi := &file_loki_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Edge) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Edge) ProtoMessage() {}
+
+func (x *Edge) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Edge.ProtoReflect.Descriptor instead.
+func (*Edge) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Edge) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *Edge) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Edge) GetSrcId() string {
+	if x != nil {
+		return x.SrcId
+	}
+	return ""
+}
+
+func (x *Edge) GetSrcType() string {
+	if x != nil && x.SrcType != nil {
+		return *x.SrcType
+	}
+	return ""
+}
+
+func (x *Edge) GetDestId() string {
+	if x != nil {
+		return x.DestId
+	}
+	return ""
+}
+
+func (x *Edge) GetDestType() string {
+	if x != nil && x.DestType != nil {
+		return *x.DestType
+	}
+	return ""
+}
+
+func (x *Edge) GetQualifier() string {
+	if x != nil && x.Qualifier != nil {
+		return *x.Qualifier
+	}
+	return ""
+}
+
+func (x *Edge) GetScore() float32 {
+	if x != nil {
+		return x.Score
+	}
+	return 0
+}
+
+func (x *Edge) GetData() *structpb.Struct {
+	if x != nil {
+		return x.Data
+	}
+	return nil
+}
+
+func (x *Edge) GetStatus() string {
+	if x != nil {
+		return x.Status
+	}
+	return ""
+}
+
+func (x *Edge) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *Edge) GetIf() *Precondition {
+	if x != nil {
+		return x.If
+	}
+	return nil
+}
+
+type Precondition struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Exists        *bool                  `protobuf:"varint,1,opt,name=exists,proto3,oneof" json:"exists,omitempty"`
+	Status        *string                `protobuf:"bytes,2,opt,name=status,proto3,oneof" json:"status,omitempty"`
+	Updated       *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=updated,proto3" json:"updated,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Precondition) Reset() {
+	*x = Precondition{}
+	mi := &file_loki_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Precondition) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Precondition) ProtoMessage() {}
+
+func (x *Precondition) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Precondition.ProtoReflect.Descriptor instead.
+func (*Precondition) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Precondition) GetExists() bool {
+	if x != nil && x.Exists != nil {
+		return *x.Exists
+	}
+	return false
+}
+
+func (x *Precondition) GetStatus() string {
+	if x != nil && x.Status != nil {
+		return *x.Status
+	}
+	return ""
+}
+
+func (x *Precondition) GetUpdated() *timestamppb.Timestamp {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+type EdgeResult struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Id            string                 `protobuf:"bytes,2,opt,name=id,proto3" json:"id,omitempty"`
+	Outcome       string                 `protobuf:"bytes,3,opt,name=outcome,proto3" json:"outcome,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EdgeResult) Reset() {
+	*x = EdgeResult{}
+	mi := &file_loki_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EdgeResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EdgeResult) ProtoMessage() {}
+
+func (x *EdgeResult) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EdgeResult.ProtoReflect.Descriptor instead.
+func (*EdgeResult) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *EdgeResult) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *EdgeResult) GetId() string {
+	if x != nil {
+		return x.Id
+	}
+	return ""
+}
+
+func (x *EdgeResult) GetOutcome() string {
+	if x != nil {
+		return x.Outcome
+	}
+	return ""
+}
+
+type InitEdgeRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Namespace     *string                `protobuf:"bytes,2,opt,name=namespace,proto3,oneof" json:"namespace,omitempty"`
+	Counters      bool                   `protobuf:"varint,3,opt,name=counters,proto3" json:"counters,omitempty"`
+	Qualified     bool                   `protobuf:"varint,4,opt,name=qualified,proto3" json:"qualified,omitempty"`
+	Symmetric     bool                   `protobuf:"varint,5,opt,name=symmetric,proto3" json:"symmetric,omitempty"`
+	Inverse       string                 `protobuf:"bytes,6,opt,name=inverse,proto3" json:"inverse,omitempty"`
+	IdType        string                 `protobuf:"bytes,7,opt,name=id_type,json=idType,proto3" json:"id_type,omitempty"`
+	SrcTypes      []string               `protobuf:"bytes,8,rep,name=src_types,json=srcTypes,proto3" json:"src_types,omitempty"`
+	DestTypes     []string               `protobuf:"bytes,9,rep,name=dest_types,json=destTypes,proto3" json:"dest_types,omitempty"`
+	Schema        *structpb.Struct       `protobuf:"bytes,10,opt,name=schema,proto3" json:"schema,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InitEdgeRequest) Reset() {
+	*x = InitEdgeRequest{}
+	mi := &file_loki_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InitEdgeRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitEdgeRequest) ProtoMessage() {}
+
+func (x *InitEdgeRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitEdgeRequest.ProtoReflect.Descriptor instead.
+func (*InitEdgeRequest) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *InitEdgeRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *InitEdgeRequest) GetNamespace() string {
+	if x != nil && x.Namespace != nil {
+		return *x.Namespace
+	}
+	return ""
+}
+
+func (x *InitEdgeRequest) GetCounters() bool {
+	if x != nil {
+		return x.Counters
+	}
+	return false
+}
+
+func (x *InitEdgeRequest) GetQualified() bool {
+	if x != nil {
+		return x.Qualified
+	}
+	return false
+}
+
+func (x *InitEdgeRequest) GetSymmetric() bool {
+	if x != nil {
+		return x.Symmetric
+	}
+	return false
+}
+
+func (x *InitEdgeRequest) GetInverse() string {
+	if x != nil {
+		return x.Inverse
+	}
+	return ""
+}
+
+func (x *InitEdgeRequest) GetIdType() string {
+	if x != nil {
+		return x.IdType
+	}
+	return ""
+}
+
+func (x *InitEdgeRequest) GetSrcTypes() []string {
+	if x != nil {
+		return x.SrcTypes
+	}
+	return nil
+}
+
+func (x *InitEdgeRequest) GetDestTypes() []string {
+	if x != nil {
+		return x.DestTypes
+	}
+	return nil
+}
+
+func (x *InitEdgeRequest) GetSchema() *structpb.Struct {
+	if x != nil {
+		return x.Schema
+	}
+	return nil
+}
+
+type InitEdgeResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// the `namespace.name` the edges of the type are saved with
+	Name          string `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *InitEdgeResponse) Reset() {
+	*x = InitEdgeResponse{}
+	mi := &file_loki_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *InitEdgeResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*InitEdgeResponse) ProtoMessage() {}
+
+func (x *InitEdgeResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use InitEdgeResponse.ProtoReflect.Descriptor instead.
+func (*InitEdgeResponse) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *InitEdgeResponse) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+type EdgesRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     *string                `protobuf:"bytes,1,opt,name=namespace,proto3,oneof" json:"namespace,omitempty"`
+	Edges         []*Edge                `protobuf:"bytes,2,rep,name=edges,proto3" json:"edges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EdgesRequest) Reset() {
+	*x = EdgesRequest{}
+	mi := &file_loki_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EdgesRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EdgesRequest) ProtoMessage() {}
+
+func (x *EdgesRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EdgesRequest.ProtoReflect.Descriptor instead.
+func (*EdgesRequest) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *EdgesRequest) GetNamespace() string {
+	if x != nil && x.Namespace != nil {
+		return *x.Namespace
+	}
+	return ""
+}
+
+func (x *EdgesRequest) GetEdges() []*Edge {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+type EdgesResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*EdgeResult          `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *EdgesResponse) Reset() {
+	*x = EdgesResponse{}
+	mi := &file_loki_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *EdgesResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*EdgesResponse) ProtoMessage() {}
+
+func (x *EdgesResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use EdgesResponse.ProtoReflect.Descriptor instead.
+func (*EdgesResponse) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *EdgesResponse) GetResults() []*EdgeResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type ScoreRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Min           *float64               `protobuf:"fixed64,1,opt,name=min,proto3,oneof" json:"min,omitempty"`
+	Max           *float64               `protobuf:"fixed64,2,opt,name=max,proto3,oneof" json:"max,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ScoreRange) Reset() {
+	*x = ScoreRange{}
+	mi := &file_loki_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ScoreRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ScoreRange) ProtoMessage() {}
+
+func (x *ScoreRange) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ScoreRange.ProtoReflect.Descriptor instead.
+func (*ScoreRange) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *ScoreRange) GetMin() float64 {
+	if x != nil && x.Min != nil {
+		return *x.Min
+	}
+	return 0
+}
+
+func (x *ScoreRange) GetMax() float64 {
+	if x != nil && x.Max != nil {
+		return *x.Max
+	}
+	return 0
+}
+
+type TimeRange struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	From          *timestamppb.Timestamp `protobuf:"bytes,1,opt,name=from,proto3" json:"from,omitempty"`
+	To            *timestamppb.Timestamp `protobuf:"bytes,2,opt,name=to,proto3" json:"to,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *TimeRange) Reset() {
+	*x = TimeRange{}
+	mi := &file_loki_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *TimeRange) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*TimeRange) ProtoMessage() {}
+
+func (x *TimeRange) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use TimeRange.ProtoReflect.Descriptor instead.
+func (*TimeRange) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *TimeRange) GetFrom() *timestamppb.Timestamp {
+	if x != nil {
+		return x.From
+	}
+	return nil
+}
+
+func (x *TimeRange) GetTo() *timestamppb.Timestamp {
+	if x != nil {
+		return x.To
+	}
+	return nil
+}
+
+type QueryRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	SrcIds        []string               `protobuf:"bytes,2,rep,name=src_ids,json=srcIds,proto3" json:"src_ids,omitempty"`
+	DestIds       []string               `protobuf:"bytes,3,rep,name=dest_ids,json=destIds,proto3" json:"dest_ids,omitempty"`
+	SrcType       *string                `protobuf:"bytes,4,opt,name=src_type,json=srcType,proto3,oneof" json:"src_type,omitempty"`
+	DestType      *string                `protobuf:"bytes,5,opt,name=dest_type,json=destType,proto3,oneof" json:"dest_type,omitempty"`
+	Qualifiers    []string               `protobuf:"bytes,6,rep,name=qualifiers,proto3" json:"qualifiers,omitempty"`
+	Statuses      []string               `protobuf:"bytes,7,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	Score         *ScoreRange            `protobuf:"bytes,8,opt,name=score,proto3" json:"score,omitempty"`
+	Updated       *TimeRange             `protobuf:"bytes,9,opt,name=updated,proto3" json:"updated,omitempty"`
+	OrderBy       string                 `protobuf:"bytes,10,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	Limit         int32                  `protobuf:"varint,11,opt,name=limit,proto3" json:"limit,omitempty"`
+	Cursor        string                 `protobuf:"bytes,12,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryRequest) Reset() {
+	*x = QueryRequest{}
+	mi := &file_loki_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryRequest) ProtoMessage() {}
+
+func (x *QueryRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryRequest.ProtoReflect.Descriptor instead.
+func (*QueryRequest) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *QueryRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetSrcIds() []string {
+	if x != nil {
+		return x.SrcIds
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetDestIds() []string {
+	if x != nil {
+		return x.DestIds
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetSrcType() string {
+	if x != nil && x.SrcType != nil {
+		return *x.SrcType
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetDestType() string {
+	if x != nil && x.DestType != nil {
+		return *x.DestType
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetQualifiers() []string {
+	if x != nil {
+		return x.Qualifiers
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetScore() *ScoreRange {
+	if x != nil {
+		return x.Score
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetUpdated() *TimeRange {
+	if x != nil {
+		return x.Updated
+	}
+	return nil
+}
+
+func (x *QueryRequest) GetOrderBy() string {
+	if x != nil {
+		return x.OrderBy
+	}
+	return ""
+}
+
+func (x *QueryRequest) GetLimit() int32 {
+	if x != nil {
+		return x.Limit
+	}
+	return 0
+}
+
+func (x *QueryRequest) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type QueryResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Edges []*Edge                `protobuf:"bytes,1,rep,name=edges,proto3" json:"edges,omitempty"`
+	// empty on the last page
+	Cursor        string `protobuf:"bytes,2,opt,name=cursor,proto3" json:"cursor,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *QueryResponse) Reset() {
+	*x = QueryResponse{}
+	mi := &file_loki_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *QueryResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*QueryResponse) ProtoMessage() {}
+
+func (x *QueryResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use QueryResponse.ProtoReflect.Descriptor instead.
+func (*QueryResponse) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *QueryResponse) GetEdges() []*Edge {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+func (x *QueryResponse) GetCursor() string {
+	if x != nil {
+		return x.Cursor
+	}
+	return ""
+}
+
+type CountRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	SrcIds        []string               `protobuf:"bytes,2,rep,name=src_ids,json=srcIds,proto3" json:"src_ids,omitempty"`
+	DestIds       []string               `protobuf:"bytes,3,rep,name=dest_ids,json=destIds,proto3" json:"dest_ids,omitempty"`
+	SrcType       *string                `protobuf:"bytes,4,opt,name=src_type,json=srcType,proto3,oneof" json:"src_type,omitempty"`
+	DestType      *string                `protobuf:"bytes,5,opt,name=dest_type,json=destType,proto3,oneof" json:"dest_type,omitempty"`
+	Statuses      []string               `protobuf:"bytes,6,rep,name=statuses,proto3" json:"statuses,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountRequest) Reset() {
+	*x = CountRequest{}
+	mi := &file_loki_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountRequest) ProtoMessage() {}
+
+func (x *CountRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountRequest.ProtoReflect.Descriptor instead.
+func (*CountRequest) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{11}
+}
+
+func (x *CountRequest) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *CountRequest) GetSrcIds() []string {
+	if x != nil {
+		return x.SrcIds
+	}
+	return nil
+}
+
+func (x *CountRequest) GetDestIds() []string {
+	if x != nil {
+		return x.DestIds
+	}
+	return nil
+}
+
+func (x *CountRequest) GetSrcType() string {
+	if x != nil && x.SrcType != nil {
+		return *x.SrcType
+	}
+	return ""
+}
+
+func (x *CountRequest) GetDestType() string {
+	if x != nil && x.DestType != nil {
+		return *x.DestType
+	}
+	return ""
+}
+
+func (x *CountRequest) GetStatuses() []string {
+	if x != nil {
+		return x.Statuses
+	}
+	return nil
+}
+
+type CountResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Counts        map[string]int64       `protobuf:"bytes,1,rep,name=counts,proto3" json:"counts,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"varint,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *CountResponse) Reset() {
+	*x = CountResponse{}
+	mi := &file_loki_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *CountResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*CountResponse) ProtoMessage() {}
+
+func (x *CountResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use CountResponse.ProtoReflect.Descriptor instead.
+func (*CountResponse) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *CountResponse) GetCounts() map[string]int64 {
+	if x != nil {
+		return x.Counts
+	}
+	return nil
+}
+
+type Operation struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// `save` or `delete`
+	Action        string  `protobuf:"bytes,1,opt,name=action,proto3" json:"action,omitempty"`
+	Edges         []*Edge `protobuf:"bytes,2,rep,name=edges,proto3" json:"edges,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Operation) Reset() {
+	*x = Operation{}
+	mi := &file_loki_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Operation) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Operation) ProtoMessage() {}
+
+func (x *Operation) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Operation.ProtoReflect.Descriptor instead.
+func (*Operation) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *Operation) GetAction() string {
+	if x != nil {
+		return x.Action
+	}
+	return ""
+}
+
+func (x *Operation) GetEdges() []*Edge {
+	if x != nil {
+		return x.Edges
+	}
+	return nil
+}
+
+type BatchRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Namespace     *string                `protobuf:"bytes,1,opt,name=namespace,proto3,oneof" json:"namespace,omitempty"`
+	Operations    []*Operation           `protobuf:"bytes,2,rep,name=operations,proto3" json:"operations,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchRequest) Reset() {
+	*x = BatchRequest{}
+	mi := &file_loki_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchRequest) ProtoMessage() {}
+
+func (x *BatchRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchRequest.ProtoReflect.Descriptor instead.
+func (*BatchRequest) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *BatchRequest) GetNamespace() string {
+	if x != nil && x.Namespace != nil {
+		return *x.Namespace
+	}
+	return ""
+}
+
+func (x *BatchRequest) GetOperations() []*Operation {
+	if x != nil {
+		return x.Operations
+	}
+	return nil
+}
+
+type OperationResults struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Results       []*EdgeResult          `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *OperationResults) Reset() {
+	*x = OperationResults{}
+	mi := &file_loki_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *OperationResults) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*OperationResults) ProtoMessage() {}
+
+func (x *OperationResults) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use OperationResults.ProtoReflect.Descriptor instead.
+func (*OperationResults) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *OperationResults) GetResults() []*EdgeResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type BatchResponse struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// the results of every operation, in order
+	Results       []*OperationResults `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchResponse) Reset() {
+	*x = BatchResponse{}
+	mi := &file_loki_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchResponse) ProtoMessage() {}
+
+func (x *BatchResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_loki_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchResponse.ProtoReflect.Descriptor instead.
+func (*BatchResponse) Descriptor() ([]byte, []int) {
+	return file_loki_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *BatchResponse) GetResults() []*OperationResults {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+var File_loki_proto protoreflect.FileDescriptor
+
+const file_loki_proto_rawDesc = "" +
+	"\n" +
+	"\n" +
+	"loki.proto\x12\aloki.v1\x1a\x1cgoogle/protobuf/struct.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"\xa0\x03\n" +
+	"\x04Edge\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\tR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x15\n" +
+	"\x06src_id\x18\x03 \x01(\tR\x05srcId\x12\x1e\n" +
+	"\bsrc_type\x18\x04 \x01(\tH\x00R\asrcType\x88\x01\x01\x12\x17\n" +
+	"\adest_id\x18\x05 \x01(\tR\x06destId\x12 \n" +
+	"\tdest_type\x18\x06 \x01(\tH\x01R\bdestType\x88\x01\x01\x12!\n" +
+	"\tqualifier\x18\a \x01(\tH\x02R\tqualifier\x88\x01\x01\x12\x14\n" +
+	"\x05score\x18\b \x01(\x02R\x05score\x12+\n" +
+	"\x04data\x18\t \x01(\v2\x17.google.protobuf.StructR\x04data\x12\x16\n" +
+	"\x06status\x18\n" +
+	" \x01(\tR\x06status\x124\n" +
+	"\aupdated\x18\v \x01(\v2\x1a.google.protobuf.TimestampR\aupdated\x12%\n" +
+	"\x02if\x18\f \x01(\v2\x15.loki.v1.PreconditionR\x02ifB\v\n" +
+	"\t_src_typeB\f\n" +
+	"\n" +
+	"_dest_typeB\f\n" +
+	"\n" +
+	"_qualifier\"\x94\x01\n" +
+	"\fPrecondition\x12\x1b\n" +
+	"\x06exists\x18\x01 \x01(\bH\x00R\x06exists\x88\x01\x01\x12\x1b\n" +
+	"\x06status\x18\x02 \x01(\tH\x01R\x06status\x88\x01\x01\x124\n" +
+	"\aupdated\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\aupdatedB\t\n" +
+	"\a_existsB\t\n" +
+	"\a_status\"J\n" +
+	"\n" +
+	"EdgeResult\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x0e\n" +
+	"\x02id\x18\x02 \x01(\tR\x02id\x12\x18\n" +
+	"\aoutcome\x18\x03 \x01(\tR\aoutcome\"\xce\x02\n" +
+	"\x0fInitEdgeRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12!\n" +
+	"\tnamespace\x18\x02 \x01(\tH\x00R\tnamespace\x88\x01\x01\x12\x1a\n" +
+	"\bcounters\x18\x03 \x01(\bR\bcounters\x12\x1c\n" +
+	"\tqualified\x18\x04 \x01(\bR\tqualified\x12\x1c\n" +
+	"\tsymmetric\x18\x05 \x01(\bR\tsymmetric\x12\x18\n" +
+	"\ainverse\x18\x06 \x01(\tR\ainverse\x12\x17\n" +
+	"\aid_type\x18\a \x01(\tR\x06idType\x12\x1b\n" +
+	"\tsrc_types\x18\b \x03(\tR\bsrcTypes\x12\x1d\n" +
+	"\n" +
+	"dest_types\x18\t \x03(\tR\tdestTypes\x12/\n" +
+	"\x06schema\x18\n" +
+	" \x01(\v2\x17.google.protobuf.StructR\x06schemaB\f\n" +
+	"\n" +
+	"_namespace\"&\n" +
+	"\x10InitEdgeResponse\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\"d\n" +
+	"\fEdgesRequest\x12!\n" +
+	"\tnamespace\x18\x01 \x01(\tH\x00R\tnamespace\x88\x01\x01\x12#\n" +
+	"\x05edges\x18\x02 \x03(\v2\r.loki.v1.EdgeR\x05edgesB\f\n" +
+	"\n" +
+	"_namespace\">\n" +
+	"\rEdgesResponse\x12-\n" +
+	"\aresults\x18\x01 \x03(\v2\x13.loki.v1.EdgeResultR\aresults\"J\n" +
+	"\n" +
+	"ScoreRange\x12\x15\n" +
+	"\x03min\x18\x01 \x01(\x01H\x00R\x03min\x88\x01\x01\x12\x15\n" +
+	"\x03max\x18\x02 \x01(\x01H\x01R\x03max\x88\x01\x01B\x06\n" +
+	"\x04_minB\x06\n" +
+	"\x04_max\"g\n" +
+	"\tTimeRange\x12.\n" +
+	"\x04from\x18\x01 \x01(\v2\x1a.google.protobuf.TimestampR\x04from\x12*\n" +
+	"\x02to\x18\x02 \x01(\v2\x1a.google.protobuf.TimestampR\x02to\"\x91\x03\n" +
+	"\fQueryRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x17\n" +
+	"\asrc_ids\x18\x02 \x03(\tR\x06srcIds\x12\x19\n" +
+	"\bdest_ids\x18\x03 \x03(\tR\adestIds\x12\x1e\n" +
+	"\bsrc_type\x18\x04 \x01(\tH\x00R\asrcType\x88\x01\x01\x12 \n" +
+	"\tdest_type\x18\x05 \x01(\tH\x01R\bdestType\x88\x01\x01\x12\x1e\n" +
+	"\n" +
+	"qualifiers\x18\x06 \x03(\tR\n" +
+	"qualifiers\x12\x1a\n" +
+	"\bstatuses\x18\a \x03(\tR\bstatuses\x12)\n" +
+	"\x05score\x18\b \x01(\v2\x13.loki.v1.ScoreRangeR\x05score\x12,\n" +
+	"\aupdated\x18\t \x01(\v2\x12.loki.v1.TimeRangeR\aupdated\x12\x19\n" +
+	"\border_by\x18\n" +
+	" \x01(\tR\aorderBy\x12\x14\n" +
+	"\x05limit\x18\v \x01(\x05R\x05limit\x12\x16\n" +
+	"\x06cursor\x18\f \x01(\tR\x06cursorB\v\n" +
+	"\t_src_typeB\f\n" +
+	"\n" +
+	"_dest_type\"L\n" +
+	"\rQueryResponse\x12#\n" +
+	"\x05edges\x18\x01 \x03(\v2\r.loki.v1.EdgeR\x05edges\x12\x16\n" +
+	"\x06cursor\x18\x02 \x01(\tR\x06cursor\"\xcf\x01\n" +
+	"\fCountRequest\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x17\n" +
+	"\asrc_ids\x18\x02 \x03(\tR\x06srcIds\x12\x19\n" +
+	"\bdest_ids\x18\x03 \x03(\tR\adestIds\x12\x1e\n" +
+	"\bsrc_type\x18\x04 \x01(\tH\x00R\asrcType\x88\x01\x01\x12 \n" +
+	"\tdest_type\x18\x05 \x01(\tH\x01R\bdestType\x88\x01\x01\x12\x1a\n" +
+	"\bstatuses\x18\x06 \x03(\tR\bstatusesB\v\n" +
+	"\t_src_typeB\f\n" +
+	"\n" +
+	"_dest_type\"\x86\x01\n" +
+	"\rCountResponse\x12:\n" +
+	"\x06counts\x18\x01 \x03(\v2\".loki.v1.CountResponse.CountsEntryR\x06counts\x1a9\n" +
+	"\vCountsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x03R\x05value:\x028\x01\"H\n" +
+	"\tOperation\x12\x16\n" +
+	"\x06action\x18\x01 \x01(\tR\x06action\x12#\n" +
+	"\x05edges\x18\x02 \x03(\v2\r.loki.v1.EdgeR\x05edges\"s\n" +
+	"\fBatchRequest\x12!\n" +
+	"\tnamespace\x18\x01 \x01(\tH\x00R\tnamespace\x88\x01\x01\x122\n" +
+	"\n" +
+	"operations\x18\x02 \x03(\v2\x12.loki.v1.OperationR\n" +
+	"operationsB\f\n" +
+	"\n" +
+	"_namespace\"A\n" +
+	"\x10OperationResults\x12-\n" +
+	"\aresults\x18\x01 \x03(\v2\x13.loki.v1.EdgeResultR\aresults\"D\n" +
+	"\rBatchResponse\x123\n" +
+	"\aresults\x18\x01 \x03(\v2\x19.loki.v1.OperationResultsR\aresults2\xf3\x02\n" +
+	"\x04Loki\x12?\n" +
+	"\bInitEdge\x12\x18.loki.v1.InitEdgeRequest\x1a\x19.loki.v1.InitEdgeResponse\x12:\n" +
+	"\tSaveEdges\x12\x15.loki.v1.EdgesRequest\x1a\x16.loki.v1.EdgesResponse\x12<\n" +
+	"\vDeleteEdges\x12\x15.loki.v1.EdgesRequest\x1a\x16.loki.v1.EdgesResponse\x12;\n" +
+	"\n" +
+	"QueryEdges\x12\x15.loki.v1.QueryRequest\x1a\x16.loki.v1.QueryResponse\x12;\n" +
+	"\n" +
+	"CountEdges\x12\x15.loki.v1.CountRequest\x1a\x16.loki.v1.CountResponse\x126\n" +
+	"\x05Batch\x12\x15.loki.v1.BatchRequest\x1a\x16.loki.v1.BatchResponseB\x1fZ\x1dgithub.com/sonnes/loki/rpc/pbb\x06proto3"
+
+var (
+	file_loki_proto_rawDescOnce sync.Once
+	file_loki_proto_rawDescData []byte
+)
+
+func file_loki_proto_rawDescGZIP() []byte {
+	file_loki_proto_rawDescOnce.Do(func() {
+		file_loki_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_loki_proto_rawDesc), len(file_loki_proto_rawDesc)))
+	})
+	return file_loki_proto_rawDescData
+}
+
+var file_loki_proto_msgTypes = make([]protoimpl.MessageInfo, 18)
+var file_loki_proto_goTypes = []any{
+	(*Edge)(nil),                  // 0: loki.v1.Edge
+	(*Precondition)(nil),          // 1: loki.v1.Precondition
+	(*EdgeResult)(nil),            // 2: loki.v1.EdgeResult
+	(*InitEdgeRequest)(nil),       // 3: loki.v1.InitEdgeRequest
+	(*InitEdgeResponse)(nil),      // 4: loki.v1.InitEdgeResponse
+	(*EdgesRequest)(nil),          // 5: loki.v1.EdgesRequest
+	(*EdgesResponse)(nil),         // 6: loki.v1.EdgesResponse
+	(*ScoreRange)(nil),            // 7: loki.v1.ScoreRange
+	(*TimeRange)(nil),             // 8: loki.v1.TimeRange
+	(*QueryRequest)(nil),          // 9: loki.v1.QueryRequest
+	(*QueryResponse)(nil),         // 10: loki.v1.QueryResponse
+	(*CountRequest)(nil),          // 11: loki.v1.CountRequest
+	(*CountResponse)(nil),         // 12: loki.v1.CountResponse
+	(*Operation)(nil),             // 13: loki.v1.Operation
+	(*BatchRequest)(nil),          // 14: loki.v1.BatchRequest
+	(*OperationResults)(nil),      // 15: loki.v1.OperationResults
+	(*BatchResponse)(nil),         // 16: loki.v1.BatchResponse
+	nil,                           // 17: loki.v1.CountResponse.CountsEntry
+	(*structpb.Struct)(nil),       // 18: google.protobuf.Struct
+	(*timestamppb.Timestamp)(nil), // 19: google.protobuf.Timestamp
+}
+var file_loki_proto_depIdxs = []int32{
+	18, // 0: loki.v1.Edge.data:type_name -> google.protobuf.Struct
+	19, // 1: loki.v1.Edge.updated:type_name -> google.protobuf.Timestamp
+	1,  // 2: loki.v1.Edge.if:type_name -> loki.v1.Precondition
+	19, // 3: loki.v1.Precondition.updated:type_name -> google.protobuf.Timestamp
+	18, // 4: loki.v1.InitEdgeRequest.schema:type_name -> google.protobuf.Struct
+	0,  // 5: loki.v1.EdgesRequest.edges:type_name -> loki.v1.Edge
+	2,  // 6: loki.v1.EdgesResponse.results:type_name -> loki.v1.EdgeResult
+	19, // 7: loki.v1.TimeRange.from:type_name -> google.protobuf.Timestamp
+	19, // 8: loki.v1.TimeRange.to:type_name -> google.protobuf.Timestamp
+	7,  // 9: loki.v1.QueryRequest.score:type_name -> loki.v1.ScoreRange
+	8,  // 10: loki.v1.QueryRequest.updated:type_name -> loki.v1.TimeRange
+	0,  // 11: loki.v1.QueryResponse.edges:type_name -> loki.v1.Edge
+	17, // 12: loki.v1.CountResponse.counts:type_name -> loki.v1.CountResponse.CountsEntry
+	0,  // 13: loki.v1.Operation.edges:type_name -> loki.v1.Edge
+	13, // 14: loki.v1.BatchRequest.operations:type_name -> loki.v1.Operation
+	2,  // 15: loki.v1.OperationResults.results:type_name -> loki.v1.EdgeResult
+	15, // 16: loki.v1.BatchResponse.results:type_name -> loki.v1.OperationResults
+	3,  // 17: loki.v1.Loki.InitEdge:input_type -> loki.v1.InitEdgeRequest
+	5,  // 18: loki.v1.Loki.SaveEdges:input_type -> loki.v1.EdgesRequest
+	5,  // 19: loki.v1.Loki.DeleteEdges:input_type -> loki.v1.EdgesRequest
+	9,  // 20: loki.v1.Loki.QueryEdges:input_type -> loki.v1.QueryRequest
+	11, // 21: loki.v1.Loki.CountEdges:input_type -> loki.v1.CountRequest
+	14, // 22: loki.v1.Loki.Batch:input_type -> loki.v1.BatchRequest
+	4,  // 23: loki.v1.Loki.InitEdge:output_type -> loki.v1.InitEdgeResponse
+	6,  // 24: loki.v1.Loki.SaveEdges:output_type -> loki.v1.EdgesResponse
+	6,  // 25: loki.v1.Loki.DeleteEdges:output_type -> loki.v1.EdgesResponse
+	10, // 26: loki.v1.Loki.QueryEdges:output_type -> loki.v1.QueryResponse
+	12, // 27: loki.v1.Loki.CountEdges:output_type -> loki.v1.CountResponse
+	16, // 28: loki.v1.Loki.Batch:output_type -> loki.v1.BatchResponse
+	23, // [23:29] is the sub-list for method output_type
+	17, // [17:23] is the sub-list for method input_type
+	17, // [17:17] is the sub-list for extension type_name
+	17, // [17:17] is the sub-list for extension extendee
+	0,  // [0:17] is the sub-list for field type_name
+}
+
+func init() { file_loki_proto_init() }
+func file_loki_proto_init() {
+	if File_loki_proto != nil {
+		return
+	}
+	file_loki_proto_msgTypes[0].OneofWrappers = []any{}
+	file_loki_proto_msgTypes[1].OneofWrappers = []any{}
+	file_loki_proto_msgTypes[3].OneofWrappers = []any{}
+	file_loki_proto_msgTypes[5].OneofWrappers = []any{}
+	file_loki_proto_msgTypes[7].OneofWrappers = []any{}
+	file_loki_proto_msgTypes[9].OneofWrappers = []any{}
+	file_loki_proto_msgTypes[11].OneofWrappers = []any{}
+	file_loki_proto_msgTypes[14].OneofWrappers = []any{}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_loki_proto_rawDesc), len(file_loki_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   18,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_loki_proto_goTypes,
+		DependencyIndexes: file_loki_proto_depIdxs,
+		MessageInfos:      file_loki_proto_msgTypes,
+	}.Build()
+	File_loki_proto = out.File
+	file_loki_proto_goTypes = nil
+	file_loki_proto_depIdxs = nil
+}
@@ -0,0 +1,321 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.5.1
+// - protoc             (unknown)
+// source: loki.proto
+
+package pb
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	Loki_InitEdge_FullMethodName    = "/loki.v1.Loki/InitEdge"
+	Loki_SaveEdges_FullMethodName   = "/loki.v1.Loki/SaveEdges"
+	Loki_DeleteEdges_FullMethodName = "/loki.v1.Loki/DeleteEdges"
+	Loki_QueryEdges_FullMethodName  = "/loki.v1.Loki/QueryEdges"
+	Loki_CountEdges_FullMethodName  = "/loki.v1.Loki/CountEdges"
+	Loki_Batch_FullMethodName       = "/loki.v1.Loki/Batch"
+)
+
+// LokiClient is the client API for Loki service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// Loki is the gRPC API of the edge store, served along with the
+// HTTP API. Requests are validated & written like their HTTP
+// counterparts, errors are returned with the status code matching
+// the HTTP status.
+type LokiClient interface {
+	InitEdge(ctx context.Context, in *InitEdgeRequest, opts ...grpc.CallOption) (*InitEdgeResponse, error)
+	SaveEdges(ctx context.Context, in *EdgesRequest, opts ...grpc.CallOption) (*EdgesResponse, error)
+	DeleteEdges(ctx context.Context, in *EdgesRequest, opts ...grpc.CallOption) (*EdgesResponse, error)
+	QueryEdges(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error)
+	CountEdges(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountResponse, error)
+	Batch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error)
+}
+
+type lokiClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewLokiClient(cc grpc.ClientConnInterface) LokiClient {
+	return &lokiClient{cc}
+}
+
+func (c *lokiClient) InitEdge(ctx context.Context, in *InitEdgeRequest, opts ...grpc.CallOption) (*InitEdgeResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(InitEdgeResponse)
+	err := c.cc.Invoke(ctx, Loki_InitEdge_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lokiClient) SaveEdges(ctx context.Context, in *EdgesRequest, opts ...grpc.CallOption) (*EdgesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EdgesResponse)
+	err := c.cc.Invoke(ctx, Loki_SaveEdges_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lokiClient) DeleteEdges(ctx context.Context, in *EdgesRequest, opts ...grpc.CallOption) (*EdgesResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(EdgesResponse)
+	err := c.cc.Invoke(ctx, Loki_DeleteEdges_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lokiClient) QueryEdges(ctx context.Context, in *QueryRequest, opts ...grpc.CallOption) (*QueryResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(QueryResponse)
+	err := c.cc.Invoke(ctx, Loki_QueryEdges_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lokiClient) CountEdges(ctx context.Context, in *CountRequest, opts ...grpc.CallOption) (*CountResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(CountResponse)
+	err := c.cc.Invoke(ctx, Loki_CountEdges_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *lokiClient) Batch(ctx context.Context, in *BatchRequest, opts ...grpc.CallOption) (*BatchResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchResponse)
+	err := c.cc.Invoke(ctx, Loki_Batch_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// LokiServer is the server API for Loki service.
+// All implementations must embed UnimplementedLokiServer
+// for forward compatibility.
+//
+// Loki is the gRPC API of the edge store, served along with the
+// HTTP API. Requests are validated & written like their HTTP
+// counterparts, errors are returned with the status code matching
+// the HTTP status.
+type LokiServer interface {
+	InitEdge(context.Context, *InitEdgeRequest) (*InitEdgeResponse, error)
+	SaveEdges(context.Context, *EdgesRequest) (*EdgesResponse, error)
+	DeleteEdges(context.Context, *EdgesRequest) (*EdgesResponse, error)
+	QueryEdges(context.Context, *QueryRequest) (*QueryResponse, error)
+	CountEdges(context.Context, *CountRequest) (*CountResponse, error)
+	Batch(context.Context, *BatchRequest) (*BatchResponse, error)
+	mustEmbedUnimplementedLokiServer()
+}
+
+// UnimplementedLokiServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedLokiServer struct{}
+
+func (UnimplementedLokiServer) InitEdge(context.Context, *InitEdgeRequest) (*InitEdgeResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method InitEdge not implemented")
+}
+func (UnimplementedLokiServer) SaveEdges(context.Context, *EdgesRequest) (*EdgesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method SaveEdges not implemented")
+}
+func (UnimplementedLokiServer) DeleteEdges(context.Context, *EdgesRequest) (*EdgesResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method DeleteEdges not implemented")
+}
+func (UnimplementedLokiServer) QueryEdges(context.Context, *QueryRequest) (*QueryResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method QueryEdges not implemented")
+}
+func (UnimplementedLokiServer) CountEdges(context.Context, *CountRequest) (*CountResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method CountEdges not implemented")
+}
+func (UnimplementedLokiServer) Batch(context.Context, *BatchRequest) (*BatchResponse, error) {
+	return nil, status.Errorf(codes.Unimplemented, "method Batch not implemented")
+}
+func (UnimplementedLokiServer) mustEmbedUnimplementedLokiServer() {}
+func (UnimplementedLokiServer) testEmbeddedByValue()              {}
+
+// UnsafeLokiServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to LokiServer will
+// result in compilation errors.
+type UnsafeLokiServer interface {
+	mustEmbedUnimplementedLokiServer()
+}
+
+func RegisterLokiServer(s grpc.ServiceRegistrar, srv LokiServer) {
+	// If the following call pancis, it indicates UnimplementedLokiServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&Loki_ServiceDesc, srv)
+}
+
+func _Loki_InitEdge_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(InitEdgeRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LokiServer).InitEdge(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Loki_InitEdge_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LokiServer).InitEdge(ctx, req.(*InitEdgeRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Loki_SaveEdges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EdgesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LokiServer).SaveEdges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Loki_SaveEdges_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LokiServer).SaveEdges(ctx, req.(*EdgesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Loki_DeleteEdges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(EdgesRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LokiServer).DeleteEdges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Loki_DeleteEdges_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LokiServer).DeleteEdges(ctx, req.(*EdgesRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Loki_QueryEdges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(QueryRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LokiServer).QueryEdges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Loki_QueryEdges_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LokiServer).QueryEdges(ctx, req.(*QueryRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Loki_CountEdges_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(CountRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LokiServer).CountEdges(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Loki_CountEdges_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LokiServer).CountEdges(ctx, req.(*CountRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _Loki_Batch_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(LokiServer).Batch(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: Loki_Batch_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(LokiServer).Batch(ctx, req.(*BatchRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+// Loki_ServiceDesc is the grpc.ServiceDesc for Loki service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var Loki_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "loki.v1.Loki",
+	HandlerType: (*LokiServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "InitEdge",
+			Handler:    _Loki_InitEdge_Handler,
+		},
+		{
+			MethodName: "SaveEdges",
+			Handler:    _Loki_SaveEdges_Handler,
+		},
+		{
+			MethodName: "DeleteEdges",
+			Handler:    _Loki_DeleteEdges_Handler,
+		},
+		{
+			MethodName: "QueryEdges",
+			Handler:    _Loki_QueryEdges_Handler,
+		},
+		{
+			MethodName: "CountEdges",
+			Handler:    _Loki_CountEdges_Handler,
+		},
+		{
+			MethodName: "Batch",
+			Handler:    _Loki_Batch_Handler,
+		},
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "loki.proto",
+}
@@ -0,0 +1,256 @@
+package rpc
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/sonnes/loki/handlers"
+	"github.com/sonnes/loki/models"
+	"github.com/sonnes/loki/rpc/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// Methods that only read edges, every other method is a write.
+var READ_METHODS = map[string]bool{
+	pb.Loki_QueryEdges_FullMethodName: true,
+	pb.Loki_CountEdges_FullMethodName: true,
+}
+
+// Status codes of the HTTP statuses of the handlers.
+var STATUS_CODES = map[int]codes.Code{
+	http.StatusBadRequest:            codes.InvalidArgument,
+	http.StatusUnauthorized:          codes.Unauthenticated,
+	http.StatusForbidden:             codes.PermissionDenied,
+	http.StatusNotFound:              codes.NotFound,
+	http.StatusConflict:              codes.FailedPrecondition,
+	http.StatusRequestEntityTooLarge: codes.ResourceExhausted,
+	http.StatusTooManyRequests:       codes.ResourceExhausted,
+	http.StatusNotImplemented:        codes.Unimplemented,
+}
+
+// Server serves the edges of the store over gRPC, through the same
+// operations as the HTTP endpoints, see handlers.SaveEdges.
+type Server struct {
+	pb.UnimplementedLokiServer
+
+	store         models.EdgeStore
+	apiKeys       bool
+	rateLimits    *handlers.RateLimits
+	requestLimits *handlers.RequestLimits
+}
+
+// CreateServer is the gRPC counterpart of handlers.CreateRouter,
+// with the API keys & the limits configured alike. Give both the
+// same rate limits, so every client has one budget.
+func CreateServer(store models.EdgeStore, rateLimits *handlers.RateLimits) *grpc.Server {
+
+	requestLimits, err := handlers.RequestLimitsFromEnv()
+
+	if err != nil {
+		log.Fatalf("could not read the request limits: %v\n", err)
+	}
+
+	server := &Server{
+		store:         store,
+		apiKeys:       os.Getenv("API_KEYS_ENABLED") == "true",
+		rateLimits:    rateLimits,
+		requestLimits: requestLimits,
+	}
+
+	grpcServer := grpc.NewServer(
+		grpc.MaxRecvMsgSize(int(requestLimits.BodyBytes)),
+		grpc.UnaryInterceptor(server.intercept),
+	)
+
+	pb.RegisterLokiServer(grpcServer, server)
+
+	return grpcServer
+}
+
+// intercept authenticates & limits every call, like the middleware
+// of the HTTP API.
+func (server *Server) intercept(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+
+	ctx = handlers.WithRequestLimits(ctx, server.requestLimits)
+
+	address := ""
+
+	if client, ok := peer.FromContext(ctx); ok {
+		address = client.Addr.String()
+	}
+
+	read := READ_METHODS[info.FullMethod]
+
+	if server.apiKeys {
+
+		key, err := handlers.TokenKey(server.store, requestToken(ctx))
+
+		if err != nil {
+			return nil, statusError(server.rateLimits.Unauthenticated(ctx, address, read, err))
+		}
+
+		ctx = handlers.WithKey(ctx, key)
+	}
+
+	ctx, err := server.rateLimits.Admit(ctx, handlers.ClientKey(ctx, address), read)
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return handler(ctx, req)
+}
+
+// requestToken reads the token from the `x-api-key` metadata, or
+// from a bearer token.
+func requestToken(ctx context.Context) string {
+
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if tokens := md.Get(handlers.API_KEY_HEADER); len(tokens) > 0 && tokens[0] != "" {
+		return tokens[0]
+	}
+
+	if authorizations := md.Get("authorization"); len(authorizations) > 0 && strings.HasPrefix(authorizations[0], "Bearer ") {
+		return strings.TrimPrefix(authorizations[0], "Bearer ")
+	}
+
+	return ""
+}
+
+// statusError is the status of an error of the handlers, with the
+// offending fields of invalid requests & when to retry calls that
+// were rate limited.
+func statusError(err error) error {
+
+	appErr := handlers.ModelAppError(err)
+
+	code, ok := STATUS_CODES[appErr.Code]
+
+	if !ok {
+		code = codes.Internal
+	}
+
+	st := status.New(code, appErr.Message)
+
+	if appErr.Fields != nil {
+
+		violations := make([]*errdetails.BadRequest_FieldViolation, len(*appErr.Fields))
+
+		for idx, field := range *appErr.Fields {
+			violations[idx] = &errdetails.BadRequest_FieldViolation{Field: field, Description: appErr.Message}
+		}
+
+		if detailed, detailsErr := st.WithDetails(&errdetails.BadRequest{FieldViolations: violations}); detailsErr == nil {
+			st = detailed
+		}
+	}
+
+	if limitedErr, ok := err.(*handlers.RateLimitedError); ok {
+
+		if detailed, detailsErr := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(limitedErr.Wait)}); detailsErr == nil {
+			st = detailed
+		}
+	}
+
+	return st.Err()
+}
+
+func (server *Server) InitEdge(ctx context.Context, request *pb.InitEdgeRequest) (*pb.InitEdgeResponse, error) {
+
+	edgeType, err := handlers.InitEdge(ctx, server.store, toInitEdgeRequest(request))
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pb.InitEdgeResponse{Name: edgeType.FullName()}, nil
+}
+
+func (server *Server) SaveEdges(ctx context.Context, request *pb.EdgesRequest) (*pb.EdgesResponse, error) {
+
+	results, err := handlers.SaveEdges(ctx, server.store, &handlers.EdgesListRequest{
+		Namespace: request.Namespace,
+		Edges:     toEdges(request.Edges),
+	})
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pb.EdgesResponse{Results: fromResults(results)}, nil
+}
+
+func (server *Server) DeleteEdges(ctx context.Context, request *pb.EdgesRequest) (*pb.EdgesResponse, error) {
+
+	results, err := handlers.DeleteEdges(ctx, server.store, &handlers.EdgesListRequest{
+		Namespace: request.Namespace,
+		Edges:     toEdges(request.Edges),
+	})
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pb.EdgesResponse{Results: fromResults(results)}, nil
+}
+
+func (server *Server) QueryEdges(ctx context.Context, request *pb.QueryRequest) (*pb.QueryResponse, error) {
+
+	edgeListPtr, cursor, err := handlers.QueryEdges(ctx, server.store, toEdgeQuery(request))
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	edges, err := fromEdges(*edgeListPtr)
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	return &pb.QueryResponse{Edges: edges, Cursor: cursor}, nil
+}
+
+func (server *Server) CountEdges(ctx context.Context, request *pb.CountRequest) (*pb.CountResponse, error) {
+
+	counts, err := handlers.CountEdges(ctx, server.store, toCountQuery(request))
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &pb.CountResponse{Counts: make(map[string]int64, len(counts))}
+
+	for nodeId, count := range counts {
+		response.Counts[string(nodeId)] = count
+	}
+
+	return response, nil
+}
+
+func (server *Server) Batch(ctx context.Context, request *pb.BatchRequest) (*pb.BatchResponse, error) {
+
+	results, err := handlers.BatchEdges(ctx, server.store, toBatch(request))
+
+	if err != nil {
+		return nil, statusError(err)
+	}
+
+	response := &pb.BatchResponse{Results: make([]*pb.OperationResults, len(results))}
+
+	for idx, operationResults := range results {
+		response.Results[idx] = &pb.OperationResults{Results: fromResults(operationResults)}
+	}
+
+	return response, nil
+}
@@ -0,0 +1,258 @@
+package rpc
+
+import (
+	"bytes"
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/sonnes/loki/handlers"
+	"github.com/sonnes/loki/models"
+	"github.com/sonnes/loki/rpc/pb"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+// testClient serves the store on an in-memory listener.
+func testClient(t *testing.T, store models.EdgeStore) pb.LokiClient {
+	return limitedClient(t, store, &handlers.RateLimits{})
+}
+
+// limitedClient serves the store with the rate limits.
+func limitedClient(t *testing.T, store models.EdgeStore, limits *handlers.RateLimits) pb.LokiClient {
+
+	listener := bufconn.Listen(1 << 20)
+
+	server := CreateServer(store, limits)
+
+	go server.Serve(listener)
+
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	t.Cleanup(func() { conn.Close() })
+
+	return pb.NewLokiClient(conn)
+}
+
+func TestServer_Edges(t *testing.T) {
+
+	ctx := context.Background()
+	client := testClient(t, models.NewMemoryStore())
+
+	initRes, err := client.InitEdge(ctx, &pb.InitEdgeRequest{Name: "follows", Namespace: &[]string{"test_rpc"}[0]})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if initRes.Name != "test_rpc.follows" {
+		t.Errorf("Expected the edge to be named test_rpc.follows, got %s", initRes.Name)
+	}
+
+	data, _ := structpb.NewStruct(map[string]interface{}{"via": "search"})
+
+	saveRes, err := client.SaveEdges(ctx, &pb.EdgesRequest{
+		Namespace: &[]string{"test_rpc"}[0],
+		Edges: []*pb.Edge{
+			{Name: "follows", SrcId: "1", DestId: "2", Data: data},
+			{Name: "follows", SrcId: "1", DestId: "3"},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(saveRes.Results) != 2 || saveRes.Results[0].Outcome != models.CREATED {
+		t.Errorf("Expected both edges to be created, got %v", saveRes.Results)
+	}
+
+	queryRes, err := client.QueryEdges(ctx, &pb.QueryRequest{Name: "test_rpc.follows", SrcIds: []string{"1"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(queryRes.Edges) != 2 {
+		t.Fatalf("Expected 2 edges, got %d", len(queryRes.Edges))
+	}
+
+	if via := queryRes.Edges[0].Data.AsMap()["via"]; queryRes.Edges[0].DestId != "2" || via != "search" {
+		t.Errorf("Expected the edge to 2 with its data, got %v", queryRes.Edges[0])
+	}
+
+	batchRes, err := client.Batch(ctx, &pb.BatchRequest{
+		Operations: []*pb.Operation{
+			{Action: models.DELETE, Edges: []*pb.Edge{{Name: "test_rpc.follows", SrcId: "1", DestId: "3"}}},
+			{Action: models.SAVE, Edges: []*pb.Edge{{Name: "test_rpc.follows", SrcId: "4", DestId: "2"}}},
+		},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(batchRes.Results) != 2 || batchRes.Results[0].Results[0].Outcome != models.DELETED {
+		t.Errorf("Expected the results of both operations, got %v", batchRes.Results)
+	}
+
+	countRes, err := client.CountEdges(ctx, &pb.CountRequest{Name: "test_rpc.follows", DestIds: []string{"2", "3"}})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if countRes.Counts["2"] != 2 || countRes.Counts["3"] != 0 {
+		t.Errorf("Expected 2 active edges to 2 & none to 3, got %v", countRes.Counts)
+	}
+}
+
+func TestServer_Errors(t *testing.T) {
+
+	ctx := context.Background()
+	store := models.NewMemoryStore()
+
+	_ = store.CreateType(&models.EdgeType{Name: "test_rpc_errors"})
+
+	client := testClient(t, store)
+
+	_, err := client.SaveEdges(ctx, &pb.EdgesRequest{Edges: []*pb.Edge{{Name: "test_rpc_errors", SrcId: "1"}}})
+
+	st := status.Convert(err)
+
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("Expected InvalidArgument for an edge without `dest_id`, got %v", st)
+	}
+
+	details := st.Details()
+
+	if len(details) != 1 || details[0].(*errdetails.BadRequest).FieldViolations[0].Field != "edges.0.name" {
+		t.Errorf("Expected the offending field in the details, got %v", details)
+	}
+
+	_, err = client.SaveEdges(ctx, &pb.EdgesRequest{Edges: []*pb.Edge{{Name: "test_rpc_missing", SrcId: "1", DestId: "2"}}})
+
+	if code := status.Code(err); code != codes.NotFound {
+		t.Errorf("Expected NotFound for an unknown edge, got %v", code)
+	}
+
+	_, err = client.QueryEdges(ctx, &pb.QueryRequest{Name: "test_rpc_errors", OrderBy: "name"})
+
+	if code := status.Code(err); code != codes.InvalidArgument {
+		t.Errorf("Expected InvalidArgument for an unknown order, got %v", code)
+	}
+}
+
+func TestServer_ApiKeys(t *testing.T) {
+
+	store := models.NewMemoryStore()
+
+	_ = store.CreateType(&models.EdgeType{Name: "test_rpc_keys"})
+
+	readerToken, err := store.CreateKey(&models.ApiKey{
+		Name:   "reader",
+		Scopes: models.Scopes{{Edges: []string{models.ALL_EDGES}, Actions: []string{models.READ}}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("API_KEYS_ENABLED", "true")
+
+	defer os.Unsetenv("API_KEYS_ENABLED")
+
+	client := testClient(t, store)
+
+	query := &pb.QueryRequest{Name: "test_rpc_keys"}
+
+	_, err = client.QueryEdges(context.Background(), query)
+
+	if code := status.Code(err); code != codes.Unauthenticated {
+		t.Errorf("Expected Unauthenticated without a key, got %v", code)
+	}
+
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", readerToken)
+
+	if _, err = client.QueryEdges(ctx, query); err != nil {
+		t.Errorf("Expected the reader to query edges, got %v", err)
+	}
+
+	_, err = client.SaveEdges(ctx, &pb.EdgesRequest{Edges: []*pb.Edge{{Name: "test_rpc_keys", SrcId: "1", DestId: "2"}}})
+
+	if code := status.Code(err); code != codes.PermissionDenied {
+		t.Errorf("Expected PermissionDenied for a write of the reader, got %v", code)
+	}
+
+	bearer := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+readerToken)
+
+	if _, err = client.CountEdges(bearer, &pb.CountRequest{Name: "test_rpc_keys", SrcIds: []string{"1"}}); err != nil {
+		t.Errorf("Expected the reader to count edges with a bearer token, got %v", err)
+	}
+}
+
+func TestServer_SharedRateLimits(t *testing.T) {
+
+	store := models.NewMemoryStore()
+
+	_ = store.CreateType(&models.EdgeType{Name: "test_rpc_limits"})
+
+	token, err := store.CreateKey(&models.ApiKey{
+		Name:   "reader",
+		Scopes: models.Scopes{{Edges: []string{models.ALL_EDGES}, Actions: []string{models.READ}}},
+	})
+
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	os.Setenv("API_KEYS_ENABLED", "true")
+
+	defer os.Unsetenv("API_KEYS_ENABLED")
+
+	limits := &handlers.RateLimits{Reads: handlers.NewRateLimiter(1)}
+
+	client := limitedClient(t, store, limits)
+	router := handlers.CreateRouter(store, limits)
+
+	req := httptest.NewRequest("POST", "/v1/edges/query", bytes.NewReader([]byte(`{ "name" : "test_rpc_limits", "src_id" : [ 1 ] }`)))
+	req.Header.Add("Content-Type", "application/json")
+	req.Header.Add(handlers.API_KEY_HEADER, token)
+
+	res := httptest.NewRecorder()
+
+	router.ServeHTTP(res, req)
+
+	if res.Code != http.StatusOK {
+		t.Fatalf("Expected the HTTP query to be allowed, got %v", res.Code)
+	}
+
+	// the HTTP query took the budget of the key
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "x-api-key", token)
+
+	_, err = client.QueryEdges(ctx, &pb.QueryRequest{Name: "test_rpc_limits", SrcIds: []string{"1"}})
+
+	if code := status.Code(err); code != codes.ResourceExhausted {
+		t.Errorf("Expected ResourceExhausted for a key out of its budget, got %v", code)
+	}
+}
@@ -1,12 +1,15 @@
 CREATE DATABASE IF NOT EXISTS edgestore ENCODING 'UTF8';
 
+-- follows are imported with the key names of entities as ids, see
+-- KeyId in cli/models.go, so follow is an edge with "id_type": "string"
 CREATE TABLE IF NOT EXISTS follow (
   id varchar PRIMARY KEY,
 
-  src_id bigint,
+  src_id varchar,
   src_type varchar,
-  dest_id bigint,
+  dest_id varchar,
   dest_type varchar,
+  qualifier varchar,
   score decimal,
   data jsonb,
   status varchar,
@@ -19,15 +22,20 @@ CREATE INDEX follow_dest_id ON follow (dest_id);
 CREATE INDEX follow_score ON follow (score);
 CREATE INDEX follow_status ON follow (status);
 CREATE INDEX follow_combi ON follow (src_id, dest_id, score, status);
+CREATE INDEX follow_src_score ON follow (src_id, score, id);
+CREATE INDEX follow_dest_score ON follow (dest_id, score, id);
+CREATE INDEX follow_src_updated ON follow (src_id, updated, id);
+CREATE INDEX follow_dest_updated ON follow (dest_id, updated, id);
 
 
 CREATE TABLE IF NOT EXISTS follow_import (
   id varchar,
 
-  src_id bigint,
+  src_id varchar,
   src_type varchar,
-  dest_id bigint,
+  dest_id varchar,
   dest_type varchar,
+  qualifier varchar,
   score decimal,
   data jsonb,
   status varchar,